@@ -0,0 +1,88 @@
+// Command accounting-export writes the ledger journals posted between two
+// IST dates, inclusive, as a generic journal CSV or a Tally XML import.
+// Ledger names come from ACCOUNTING_EXPORT_MAP.
+//
+//	go run ./cmd/accounting-export -from 2025-04-01 -to 2025-04-30 -format tally -out april.xml
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/accounting"
+	"github.com/angad363/stocky-assignment/internal/calendar"
+	"github.com/angad363/stocky-assignment/internal/config"
+	"github.com/angad363/stocky-assignment/internal/db"
+	"github.com/angad363/stocky-assignment/pkg/logger"
+)
+
+func main() {
+	fromFlag := flag.String("from", "", "first IST date, YYYY-MM-DD (default: first of this month)")
+	toFlag := flag.String("to", "", "last IST date, YYYY-MM-DD (default: today)")
+	format := flag.String("format", "csv", "csv or tally")
+	out := flag.String("out", "", "output file (default: stdout)")
+	flag.Parse()
+
+	logger.Init()
+	cfg := config.Load()
+
+	today := time.Now().In(calendar.IST)
+	from := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, calendar.IST)
+	to := today
+	for name, v := range map[string]struct {
+		value string
+		dst   *time.Time
+	}{"from": {*fromFlag, &from}, "to": {*toFlag, &to}} {
+		if v.value == "" {
+			continue
+		}
+		day, err := time.ParseInLocation("2006-01-02", v.value, calendar.IST)
+		if err != nil {
+			logger.Log.Fatalf("Invalid -%s %q, use YYYY-MM-DD", name, v.value)
+		}
+		*v.dst = day
+	}
+	if *format != "csv" && *format != "tally" {
+		logger.Log.Fatalf("Invalid -format %q, use csv or tally", *format)
+	}
+
+	exportMap, err := accounting.ParseAccountMap(cfg.AccountingExportMap)
+	if err != nil {
+		logger.Log.Fatalf("Invalid ACCOUNTING_EXPORT_MAP: %v", err)
+	}
+
+	conn := db.Connect(cfg)
+	defer conn.Close()
+
+	journals, err := accounting.NewAccountingService(conn).Journals(context.Background(), from, to)
+	if err != nil {
+		logger.Log.Fatalf("Failed to load journals: %v", err)
+	}
+
+	dst := os.Stdout
+	if *out != "" {
+		if dst, err = os.Create(*out); err != nil {
+			logger.Log.Fatalf("Failed to create %s: %v", *out, err)
+		}
+		defer dst.Close()
+	}
+	w := bufio.NewWriter(dst)
+	if *format == "tally" {
+		err = accounting.WriteTallyXML(w, journals, exportMap, cfg.TallyCompany)
+	} else {
+		err = accounting.WriteJournalCSV(w, journals, exportMap)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		logger.Log.Fatalf("Failed to write export: %v", err)
+	}
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %d journals to %s\n", len(journals), *out)
+	}
+}
@@ -0,0 +1,36 @@
+// Command audit-verify walks the audit log hash chain and exits non-zero
+// if any entry was edited or removed. Record the printed head hash
+// somewhere outside the database to also detect truncation.
+//
+//	go run ./cmd/audit-verify
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/angad363/stocky-assignment/internal/config"
+	"github.com/angad363/stocky-assignment/internal/db"
+	"github.com/angad363/stocky-assignment/pkg/logger"
+)
+
+func main() {
+	logger.Init()
+	cfg := config.Load()
+
+	conn := db.Connect(cfg)
+	defer conn.Close()
+
+	result, err := audit.NewAuditService(conn).Verify(context.Background())
+	if err != nil {
+		logger.Log.Fatalf("Failed to verify audit log: %v", err)
+	}
+
+	if !result.Valid {
+		fmt.Printf("BROKEN at entry %d after %d entries: %s\n", result.BrokenAt, result.Checked, result.Reason)
+		os.Exit(1)
+	}
+	fmt.Printf("OK: %d entries, head %d %s\n", result.Checked, result.HeadID, result.HeadHash)
+}
@@ -0,0 +1,48 @@
+// Command ledger-check verifies the double-entry books: every journal
+// balances per commodity, every ledger entry was posted, and stock
+// inventory matches the units in the ledger. It exits non-zero otherwise.
+//
+//	go run ./cmd/ledger-check
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/angad363/stocky-assignment/internal/accounting"
+	"github.com/angad363/stocky-assignment/internal/config"
+	"github.com/angad363/stocky-assignment/internal/db"
+	"github.com/angad363/stocky-assignment/pkg/logger"
+)
+
+func main() {
+	logger.Init()
+	cfg := config.Load()
+
+	conn := db.Connect(cfg)
+	defer conn.Close()
+
+	result, err := accounting.NewAccountingService(conn).Check(context.Background())
+	if err != nil {
+		logger.Log.Fatalf("Failed to check ledger: %v", err)
+	}
+
+	for _, u := range result.Unbalanced {
+		fmt.Printf("UNBALANCED journal %d: %s off by %v\n", u.JournalID, u.Commodity, u.Amount)
+	}
+	for _, id := range result.SingleLine {
+		fmt.Printf("SINGLE LINE journal %d\n", id)
+	}
+	for _, id := range result.UnpostedLedger {
+		fmt.Printf("UNPOSTED ledger entry %d\n", id)
+	}
+	for _, m := range result.InventoryMismatch {
+		fmt.Printf("INVENTORY %s: books %v, ledger %v\n", m.Symbol, m.Inventory, m.Ledger)
+	}
+
+	if !result.OK {
+		os.Exit(1)
+	}
+	fmt.Printf("OK: %d journals, %d lines\n", result.Journals, result.Lines)
+}
@@ -17,7 +17,8 @@ func main() {
 
 	conn := db.Connect(cfg)
 	defer conn.Close()
+	db.Migrate(conn)
 
-	srv := server.NewServer(logger.Log, conn)
+	srv := server.NewServer(logger.Log, conn, cfg)
 	srv.Start(cfg.ServerPort)
 }
@@ -0,0 +1,67 @@
+// Command webhook-receiver is a local endpoint for trying out Stocky
+// webhooks. It verifies the signature of every delivery and logs it.
+//
+//	WEBHOOK_SECRET=<secret> go run ./cmd/webhook-receiver -addr :9090
+package main
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/webhooks"
+	"github.com/angad363/stocky-assignment/pkg/logger"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	failRate := flag.Int("fail-every", 0, "respond 500 to every Nth delivery to exercise retries (0 = never)")
+	flag.Parse()
+
+	logger.Init()
+	secret := os.Getenv("WEBHOOK_SECRET")
+	var received atomic.Int64
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+
+		valid := webhooks.Verify(secret,
+			r.Header.Get(webhooks.HeaderTimestamp),
+			r.Header.Get(webhooks.HeaderSignature),
+			body, 5*time.Minute)
+
+		n := received.Add(1)
+		entry := logger.Log.WithFields(map[string]interface{}{
+			"event":       r.Header.Get(webhooks.HeaderEvent),
+			"delivery_id": r.Header.Get(webhooks.HeaderDelivery),
+			"valid":       valid,
+			"body":        string(body),
+		})
+
+		if !valid {
+			entry.Warn("Rejected webhook with invalid signature")
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if *failRate > 0 && n%int64(*failRate) == 0 {
+			entry.Warn("Simulating receiver failure")
+			http.Error(w, "simulated failure", http.StatusInternalServerError)
+			return
+		}
+
+		entry.Info("Webhook received")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	logger.Log.WithField("addr", *addr).Info("Webhook receiver listening")
+	if err := http.ListenAndServe(*addr, nil); err != nil {
+		logger.Log.WithError(err).Fatal("Webhook receiver stopped")
+	}
+}
@@ -0,0 +1,161 @@
+package accounting
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/price"
+)
+
+// AccountRetainedEarnings is not posted to; the balance sheet derives it
+// from income and expense balances
+const AccountRetainedEarnings = "equity:retained_earnings"
+
+// TrialBalance lists every account's balance per commodity at the end of
+// the IST date asOf
+func (s *AccountingService) TrialBalance(ctx context.Context, asOf time.Time) (TrialBalance, error) {
+	tb := TrialBalance{Lines: []TrialBalanceLine{}, Unpriced: []string{}}
+
+	day := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, asOf.Location())
+	tb.AsOf = day.Format("2006-01-02")
+
+	err := s.db.SelectContext(ctx, &tb.Lines, `
+		SELECT a.code AS account, a.type, l.commodity, SUM(l.amount) AS balance
+		FROM journal_lines l
+		JOIN journals j ON j.id = l.journal_id
+		JOIN accounts a ON a.id = l.account_id
+		WHERE j.posted_at < $1
+		GROUP BY a.code, a.type, l.commodity
+		HAVING SUM(l.amount) <> 0
+		ORDER BY a.code, l.commodity
+	`, day.AddDate(0, 0, 1))
+	if err != nil {
+		return tb, err
+	}
+
+	// Totals add up unrounded values so rounding each line to paise does
+	// not unbalance them
+	var debit, credit float64
+	quotes := map[string]*price.PriceResponse{}
+	for i := range tb.Lines {
+		line := &tb.Lines[i]
+		value := line.Balance
+		if line.Commodity != CommodityINR {
+			quote, seen := quotes[line.Commodity]
+			if !seen {
+				q, ok, err := price.ClosingPrice(ctx, s.db, line.Commodity, day)
+				if err != nil {
+					return tb, err
+				}
+				if ok {
+					quote = &q
+				} else {
+					tb.Unpriced = append(tb.Unpriced, line.Commodity)
+				}
+				quotes[line.Commodity] = quote
+			}
+			value = 0
+			if quote != nil {
+				asOf := quote.AsOf
+				line.Price = quote.Price
+				line.PriceAsOf = &asOf
+				value = line.Balance * quote.Price
+			}
+		}
+
+		if value > 0 {
+			line.Debit = round2(value)
+			debit += value
+		} else {
+			line.Credit = round2(-value)
+			credit -= value
+		}
+	}
+	tb.TotalDebit = round2(debit)
+	tb.TotalCredit = round2(credit)
+	tb.Balanced = math.Abs(debit-credit) < 0.005
+
+	return tb, nil
+}
+
+// BalanceSheet groups the trial balance into assets, liabilities and
+// equity. User holdings are combined per symbol, and income and expenses
+// roll up into retained earnings.
+func (s *AccountingService) BalanceSheet(ctx context.Context, asOf time.Time) (BalanceSheet, error) {
+	tb, err := s.TrialBalance(ctx, asOf)
+	if err != nil {
+		return BalanceSheet{}, err
+	}
+
+	bs := BalanceSheet{
+		AsOf:        tb.AsOf,
+		Assets:      []BalanceSheetLine{},
+		Liabilities: []BalanceSheetLine{},
+		Equity:      []BalanceSheetLine{},
+		Unpriced:    tb.Unpriced,
+	}
+
+	// Amounts are re-derived from balances and prices rather than the
+	// rounded trial balance columns, and rounded once per line
+	var assets, liabilities, equity, retained float64
+	section := func(lines *[]BalanceSheetLine, account, commodity string, units, value float64) {
+		for i := range *lines {
+			if (*lines)[i].Account == account && (*lines)[i].Commodity == commodity {
+				(*lines)[i].Units += units
+				(*lines)[i].Value += value
+				return
+			}
+		}
+		*lines = append(*lines, BalanceSheetLine{Account: account, Commodity: commodity, Units: units, Value: value})
+	}
+
+	for _, line := range tb.Lines {
+		value := line.Balance
+		units := 0.0
+		if line.Commodity != CommodityINR {
+			value = line.Balance * line.Price
+			units = line.Balance
+		}
+
+		account := line.Account
+		if strings.HasPrefix(account, userHoldingsPrefix) {
+			account = strings.TrimSuffix(userHoldingsPrefix, ":")
+		}
+		switch line.Type {
+		case TypeAsset:
+			section(&bs.Assets, account, line.Commodity, units, value)
+			assets += value
+		case TypeLiability:
+			section(&bs.Liabilities, account, line.Commodity, -units, -value)
+			liabilities -= value
+		case TypeEquity:
+			section(&bs.Equity, account, line.Commodity, -units, -value)
+			equity -= value
+		default: // income and expenses
+			retained -= value
+		}
+	}
+	if retained != 0 {
+		section(&bs.Equity, AccountRetainedEarnings, CommodityINR, 0, retained)
+		equity += retained
+	}
+
+	for _, lines := range [][]BalanceSheetLine{bs.Assets, bs.Liabilities, bs.Equity} {
+		for i := range lines {
+			lines[i].Units = math.Round(lines[i].Units*1000000) / 1000000
+			lines[i].Value = round2(lines[i].Value)
+		}
+	}
+	bs.TotalAssets = round2(assets)
+	bs.TotalLiabilities = round2(liabilities)
+	bs.TotalEquity = round2(equity)
+	bs.Balanced = math.Abs(assets-liabilities-equity) < 0.005
+
+	return bs, nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,234 @@
+package accounting
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+)
+
+// AccountMap names accounts in the accountants' books. Entries match an
+// account code or any code below it, e.g. "expenses:fees" also covers
+// "expenses:fees:brokerage"; the longest match wins and unmatched
+// accounts keep their code.
+type AccountMap map[string]string
+
+// ParseAccountMap reads "code=Name;code=Name"
+func ParseAccountMap(spec string) (AccountMap, error) {
+	m := AccountMap{}
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		code, name, ok := strings.Cut(entry, "=")
+		code, name = strings.TrimSpace(code), strings.TrimSpace(name)
+		if !ok || code == "" || name == "" {
+			return nil, fmt.Errorf("invalid account mapping %q, want code=Name", entry)
+		}
+		m[code] = name
+	}
+	return m, nil
+}
+
+// Name returns the mapped ledger name for an account code
+func (m AccountMap) Name(code string) string {
+	best, name := -1, code
+	for prefix, mapped := range m {
+		if (code == prefix || strings.HasPrefix(code, prefix+":")) && len(prefix) > best {
+			best, name = len(prefix), mapped
+		}
+	}
+	return name
+}
+
+// Journals loads the journals posted in the IST days from..to inclusive,
+// with their lines
+func (s *AccountingService) Journals(ctx context.Context, from, to time.Time) ([]Journal, error) {
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, calendar.IST)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, calendar.IST).AddDate(0, 0, 1)
+
+	journals := []Journal{}
+	err := s.db.SelectContext(ctx, &journals, `
+		SELECT id, kind, reward_id, ledger_entry_id, description, posted_at
+		FROM journals
+		WHERE posted_at >= $1 AND posted_at < $2
+		ORDER BY posted_at, id
+	`, start, end)
+	if err != nil || len(journals) == 0 {
+		return journals, err
+	}
+
+	var lines []struct {
+		JournalID int `db:"journal_id"`
+		Line
+	}
+	err = s.db.SelectContext(ctx, &lines, `
+		SELECT l.journal_id, a.code AS account, l.commodity, l.amount
+		FROM journal_lines l
+		JOIN journals j ON j.id = l.journal_id
+		JOIN accounts a ON a.id = l.account_id
+		WHERE j.posted_at >= $1 AND j.posted_at < $2
+		ORDER BY l.journal_id, l.id
+	`, start, end)
+	if err != nil {
+		return journals, err
+	}
+
+	index := make(map[int]int, len(journals))
+	for i, j := range journals {
+		index[j.ID] = i
+	}
+	for _, l := range lines {
+		if i, ok := index[l.JournalID]; ok {
+			journals[i].Lines = append(journals[i].Lines, l.Line)
+		}
+	}
+	return journals, nil
+}
+
+var journalCSVHeader = []string{"journal_id", "date", "kind", "reward_id", "description",
+	"account", "ledger", "commodity", "debit", "credit"}
+
+// WriteJournalCSV writes one row per journal line, stock lines in units
+func WriteJournalCSV(w io.Writer, journals []Journal, m AccountMap) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(journalCSVHeader); err != nil {
+		return err
+	}
+	for _, j := range journals {
+		rewardID := ""
+		if j.RewardID != nil {
+			rewardID = strconv.Itoa(*j.RewardID)
+		}
+		for _, l := range j.Lines {
+			debit, credit := "", ""
+			if l.Amount > 0 {
+				debit = formatAmount(l.Commodity, l.Amount)
+			} else {
+				credit = formatAmount(l.Commodity, -l.Amount)
+			}
+			writer.Write([]string{
+				strconv.Itoa(j.ID), j.PostedAt.In(calendar.IST).Format("2006-01-02"), j.Kind, rewardID,
+				j.Description, l.Account, m.Name(l.Account), l.Commodity, debit, credit,
+			})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatAmount(commodity string, v float64) string {
+	if commodity == CommodityINR {
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Tally's XML import format. Debits carry negative amounts with
+// ISDEEMEDPOSITIVE set, credits positive amounts.
+type tallyEnvelope struct {
+	XMLName xml.Name       `xml:"ENVELOPE"`
+	Request string         `xml:"HEADER>TALLYREQUEST"`
+	Report  string         `xml:"BODY>IMPORTDATA>REQUESTDESC>REPORTNAME"`
+	Company string         `xml:"BODY>IMPORTDATA>REQUESTDESC>STATICVARIABLES>SVCURRENTCOMPANY,omitempty"`
+	Message []tallyMessage `xml:"BODY>IMPORTDATA>REQUESTDATA>TALLYMESSAGE"`
+}
+
+type tallyMessage struct {
+	Voucher tallyVoucher `xml:"VOUCHER"`
+}
+
+type tallyVoucher struct {
+	VoucherType string             `xml:"VCHTYPE,attr"`
+	Action      string             `xml:"ACTION,attr"`
+	Date        string             `xml:"DATE"`
+	TypeName    string             `xml:"VOUCHERTYPENAME"`
+	Number      string             `xml:"VOUCHERNUMBER"`
+	Narration   string             `xml:"NARRATION"`
+	Entries     []tallyLedgerEntry `xml:"ALLLEDGERENTRIES.LIST"`
+}
+
+type tallyLedgerEntry struct {
+	Ledger         string `xml:"LEDGERNAME"`
+	DeemedPositive string `xml:"ISDEEMEDPOSITIVE"`
+	Amount         string `xml:"AMOUNT"`
+}
+
+// WriteTallyXML writes one Journal voucher per journal. Tally ledgers
+// hold INR only, so stock unit lines are described in the narration and
+// lines mapped to the same ledger are combined.
+func WriteTallyXML(w io.Writer, journals []Journal, m AccountMap, company string) error {
+	envelope := tallyEnvelope{Request: "Import Data", Report: "Vouchers", Company: company}
+
+	for _, j := range journals {
+		amounts := map[string]float64{}
+		narration := []string{j.Description}
+		for _, l := range j.Lines {
+			if l.Commodity != CommodityINR {
+				if l.Amount > 0 {
+					narration = append(narration, fmt.Sprintf("%s %s to %s", formatAmount(l.Commodity, l.Amount), l.Commodity, l.Account))
+				}
+				continue
+			}
+			amounts[m.Name(l.Account)] += l.Amount
+		}
+
+		ledgers := make([]string, 0, len(amounts))
+		for ledger, amount := range amounts {
+			if roundAmount(CommodityINR, amount) != 0 {
+				ledgers = append(ledgers, ledger)
+			}
+		}
+		if len(ledgers) == 0 {
+			continue
+		}
+		// Debits first, then by name, so vouchers read the same every export
+		sort.Slice(ledgers, func(a, b int) bool {
+			if (amounts[ledgers[a]] > 0) != (amounts[ledgers[b]] > 0) {
+				return amounts[ledgers[a]] > 0
+			}
+			return ledgers[a] < ledgers[b]
+		})
+
+		voucher := tallyVoucher{
+			VoucherType: "Journal",
+			Action:      "Create",
+			Date:        j.PostedAt.In(calendar.IST).Format("20060102"),
+			TypeName:    "Journal",
+			Number:      "STK-" + strconv.Itoa(j.ID),
+			Narration:   strings.Join(narration, "; "),
+		}
+		for _, ledger := range ledgers {
+			amount := amounts[ledger]
+			deemed := "No"
+			if amount > 0 {
+				deemed = "Yes"
+			}
+			voucher.Entries = append(voucher.Entries, tallyLedgerEntry{
+				Ledger:         ledger,
+				DeemedPositive: deemed,
+				Amount:         strconv.FormatFloat(-amount, 'f', 2, 64),
+			})
+		}
+		envelope.Message = append(envelope.Message, tallyMessage{Voucher: voucher})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(envelope); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,141 @@
+package accounting
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type AccountingHandler struct {
+	service   *AccountingService
+	exportMap AccountMap
+	company   string
+}
+
+func NewAccountingHandler(service *AccountingService, exportMap AccountMap, company string) *AccountingHandler {
+	return &AccountingHandler{service: service, exportMap: exportMap, company: company}
+}
+
+// CheckLedger handles GET /admin/accounting/check
+func (h *AccountingHandler) CheckLedger(c *gin.Context) {
+	result, err := h.service.Check(c.Request.Context())
+	if err != nil {
+		logger.Log.Errorf("Failed to check ledger: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check ledger"})
+		return
+	}
+	if !result.OK {
+		logger.Log.WithFields(map[string]interface{}{
+			"unbalanced": len(result.Unbalanced),
+			"unposted":   len(result.UnpostedLedger),
+			"inventory":  len(result.InventoryMismatch),
+		}).Error("Ledger invariants do not hold")
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetTrialBalance handles GET /admin/accounting/trial-balance?as_of=YYYY-MM-DD
+func (h *AccountingHandler) GetTrialBalance(c *gin.Context) {
+	asOf, ok := asOfDate(c)
+	if !ok {
+		return
+	}
+
+	tb, err := h.service.TrialBalance(c.Request.Context(), asOf)
+	if err != nil {
+		logger.Log.Errorf("Failed to build trial balance: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build trial balance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tb)
+}
+
+// GetBalanceSheet handles GET /admin/accounting/balance-sheet?as_of=YYYY-MM-DD
+func (h *AccountingHandler) GetBalanceSheet(c *gin.Context) {
+	asOf, ok := asOfDate(c)
+	if !ok {
+		return
+	}
+
+	bs, err := h.service.BalanceSheet(c.Request.Context(), asOf)
+	if err != nil {
+		logger.Log.Errorf("Failed to build balance sheet: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build balance sheet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, bs)
+}
+
+// Export handles GET /admin/accounting/export?from=YYYY-MM-DD&to=YYYY-MM-DD&format=csv|tally.
+// The period defaults to the current month to date.
+func (h *AccountingHandler) Export(c *gin.Context) {
+	today := time.Now().In(calendar.IST)
+	from := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, calendar.IST)
+	to := today
+	for param, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		if v := c.Query(param); v != "" {
+			day, err := time.ParseInLocation("2006-01-02", v, calendar.IST)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + ", use YYYY-MM-DD"})
+				return
+			}
+			*dst = day
+		}
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "tally" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or tally"})
+		return
+	}
+
+	journals, err := h.service.Journals(c.Request.Context(), from, to)
+	if err != nil {
+		logger.Log.Errorf("Failed to load journals for export: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export journals"})
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType, ext := "text/csv", "csv"
+	if format == "tally" {
+		contentType, ext = "application/xml", "xml"
+		err = WriteTallyXML(&buf, journals, h.exportMap, h.company)
+	} else {
+		err = WriteJournalCSV(&buf, journals, h.exportMap)
+	}
+	if err != nil {
+		logger.Log.Errorf("Failed to write %s journal export: %v", format, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export journals"})
+		return
+	}
+
+	filename := "journals-" + from.Format("2006-01-02") + "-" + to.Format("2006-01-02") + "." + ext
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// asOfDate reads the as_of IST date, defaulting to today
+func asOfDate(c *gin.Context) (time.Time, bool) {
+	v := c.Query("as_of")
+	if v == "" {
+		return time.Now().In(calendar.IST), true
+	}
+	day, err := time.ParseInLocation("2006-01-02", v, calendar.IST)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid as_of, use YYYY-MM-DD"})
+		return day, false
+	}
+	return day, true
+}
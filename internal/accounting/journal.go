@@ -0,0 +1,167 @@
+package accounting
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrUnbalanced     = errors.New("journal does not balance")
+	ErrUnknownAccount = errors.New("unknown account")
+	ErrTooFewLines    = errors.New("journal needs at least two lines")
+)
+
+// Residuals up to these amounts are float noise from computing amounts in
+// Go; they are posted to the rounding account instead of being rejected
+const (
+	inrTolerance   = 0.01
+	unitsTolerance = 0.000001
+)
+
+// Post writes a journal inside the caller's transaction. Lines are rounded
+// to the column precision, zero lines are dropped, and every commodity
+// must balance. A deferred trigger checks the same again at commit.
+func Post(ctx context.Context, tx *sqlx.Tx, j Journal) (int, error) {
+	lines, err := balance(j.Lines)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO journals (kind, reward_id, ledger_entry_id, description, posted_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, j.Kind, j.RewardID, j.LedgerEntryID, j.Description, j.PostedAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range lines {
+		accountID, err := accountID(ctx, tx, line.Account)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO journal_lines (journal_id, account_id, commodity, amount) VALUES ($1, $2, $3, $4)
+		`, id, accountID, line.Commodity, line.Amount); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// balance rounds and filters a journal's lines and checks that each
+// commodity sums to zero, plugging float noise to the rounding account
+func balance(in []Line) ([]Line, error) {
+	var lines []Line
+	sums := map[string]float64{}
+	for _, line := range in {
+		line.Amount = roundAmount(line.Commodity, line.Amount)
+		if line.Amount == 0 {
+			continue
+		}
+		lines = append(lines, line)
+		sums[line.Commodity] += line.Amount
+	}
+
+	for commodity, sum := range sums {
+		residual := roundAmount(commodity, sum)
+		if residual == 0 {
+			continue
+		}
+		tolerance := unitsTolerance
+		if commodity == CommodityINR {
+			tolerance = inrTolerance
+		}
+		if math.Abs(residual) > tolerance+1e-9 {
+			return nil, fmt.Errorf("%w: %s off by %v", ErrUnbalanced, commodity, residual)
+		}
+		lines = append(lines, Line{Account: AccountRounding, Commodity: commodity, Amount: -residual})
+	}
+	if len(lines) < 2 {
+		return nil, ErrTooFewLines
+	}
+	return lines, nil
+}
+
+// Reverse posts a journal negating everything already posted for a
+// reward, so its net effect on every account becomes zero
+func Reverse(ctx context.Context, tx *sqlx.Tx, rewardID int, ledgerEntryID *int, at time.Time, description string) (int, error) {
+	lines := []Line{}
+	err := tx.SelectContext(ctx, &lines, `
+		SELECT a.code AS account, l.commodity, -SUM(l.amount) AS amount
+		FROM journal_lines l
+		JOIN journals j ON j.id = l.journal_id
+		JOIN accounts a ON a.id = l.account_id
+		WHERE j.reward_id = $1
+		GROUP BY a.code, l.commodity
+		HAVING SUM(l.amount) <> 0
+		ORDER BY a.code, l.commodity
+	`, rewardID)
+	if err != nil || len(lines) == 0 {
+		return 0, err
+	}
+
+	return Post(ctx, tx, Journal{
+		Kind:          KindReversal,
+		RewardID:      &rewardID,
+		LedgerEntryID: ledgerEntryID,
+		Description:   description,
+		PostedAt:      at,
+		Lines:         lines,
+	})
+}
+
+// RewardBalance is the balance of one account and commodity across the
+// journals posted for a reward
+func RewardBalance(ctx context.Context, tx *sqlx.Tx, rewardID int, account, commodity string) (float64, error) {
+	var balance float64
+	err := tx.GetContext(ctx, &balance, `
+		SELECT COALESCE(SUM(l.amount), 0)
+		FROM journal_lines l
+		JOIN journals j ON j.id = l.journal_id
+		JOIN accounts a ON a.id = l.account_id
+		WHERE j.reward_id = $1 AND a.code = $2 AND l.commodity = $3
+	`, rewardID, account, commodity)
+	return balance, err
+}
+
+// accountID resolves an account code, opening user holdings accounts on
+// first use. Any other account has to be in the chart already.
+func accountID(ctx context.Context, tx *sqlx.Tx, code string) (int, error) {
+	var id int
+	err := tx.GetContext(ctx, &id, `SELECT id FROM accounts WHERE code = $1`, code)
+	if err == nil || !errors.Is(err, sql.ErrNoRows) {
+		return id, err
+	}
+
+	userID, err := strconv.Atoi(strings.TrimPrefix(code, userHoldingsPrefix))
+	if !strings.HasPrefix(code, userHoldingsPrefix) || err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownAccount, code)
+	}
+	err = tx.GetContext(ctx, &id, `
+		INSERT INTO accounts (code, name, type, user_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (code) DO UPDATE SET code = EXCLUDED.code
+		RETURNING id
+	`, code, fmt.Sprintf("Shares held for user %d", userID), TypeLiability, userID)
+	return id, err
+}
+
+// roundAmount rounds INR to four decimals like the ledger's INR columns,
+// and stock units to six
+func roundAmount(commodity string, v float64) float64 {
+	if commodity == CommodityINR {
+		return math.Round(v*10000) / 10000
+	}
+	return math.Round(v*1000000) / 1000000
+}
@@ -0,0 +1,123 @@
+package accounting
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBalance(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []Line
+		want  map[string]float64 // rounding plug per commodity, if any
+		err   error
+	}{
+		{
+			name: "balanced",
+			lines: []Line{
+				{Account: AccountRewards, Commodity: CommodityINR, Amount: 2875.4},
+				{Account: AccountCash, Commodity: CommodityINR, Amount: -2875.4},
+			},
+			want: map[string]float64{},
+		},
+		{
+			name: "float noise within tolerance is plugged",
+			lines: []Line{
+				{Account: AccountRewards, Commodity: CommodityINR, Amount: 100.005},
+				{Account: AccountCash, Commodity: CommodityINR, Amount: -100},
+			},
+			want: map[string]float64{CommodityINR: -0.005},
+		},
+		{
+			name: "units rounded to six decimals",
+			lines: []Line{
+				{Account: AccountStockInventory, Commodity: "RELIANCE", Amount: 1.0000005},
+				{Account: UserHoldings(7), Commodity: "RELIANCE", Amount: -1},
+			},
+			want: map[string]float64{"RELIANCE": -0.000001},
+		},
+		{
+			name: "each commodity balances separately",
+			lines: []Line{
+				{Account: AccountStockInventory, Commodity: "TCS", Amount: 2},
+				{Account: UserHoldings(7), Commodity: "TCS", Amount: -2},
+				{Account: AccountRewards, Commodity: CommodityINR, Amount: 50.004},
+				{Account: AccountCash, Commodity: CommodityINR, Amount: -50},
+			},
+			want: map[string]float64{CommodityINR: -0.004},
+		},
+		{
+			name: "zero lines are dropped",
+			lines: []Line{
+				{Account: AccountRewards, Commodity: CommodityINR, Amount: 10},
+				{Account: AccountBrokerage, Commodity: CommodityINR, Amount: 0.00001},
+				{Account: AccountCash, Commodity: CommodityINR, Amount: -10},
+			},
+			want: map[string]float64{},
+		},
+		{
+			name: "INR off by more than a paisa",
+			lines: []Line{
+				{Account: AccountRewards, Commodity: CommodityINR, Amount: 100.02},
+				{Account: AccountCash, Commodity: CommodityINR, Amount: -100},
+			},
+			err: ErrUnbalanced,
+		},
+		{
+			name: "units off by more than the precision",
+			lines: []Line{
+				{Account: AccountStockInventory, Commodity: "INFY", Amount: 1.00001},
+				{Account: UserHoldings(7), Commodity: "INFY", Amount: -1},
+			},
+			err: ErrUnbalanced,
+		},
+		{
+			name: "everything rounds away",
+			lines: []Line{
+				{Account: AccountRewards, Commodity: CommodityINR, Amount: 0.00004},
+				{Account: AccountCash, Commodity: CommodityINR, Amount: -0.00004},
+			},
+			err: ErrTooFewLines,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := balance(tt.lines)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			sums := map[string]float64{}
+			plugs := map[string]float64{}
+			for _, l := range lines {
+				if l.Amount == 0 {
+					t.Errorf("zero line kept: %+v", l)
+				}
+				sums[l.Commodity] += l.Amount
+				if l.Account == AccountRounding {
+					plugs[l.Commodity] += l.Amount
+				}
+			}
+			for commodity, sum := range sums {
+				if roundAmount(commodity, sum) != 0 {
+					t.Errorf("%s sums to %v", commodity, sum)
+				}
+			}
+			if len(plugs) != len(tt.want) {
+				t.Fatalf("plugs = %v, want %v", plugs, tt.want)
+			}
+			for commodity, want := range tt.want {
+				if roundAmount(commodity, plugs[commodity]) != want {
+					t.Errorf("%s plug = %v, want %v", commodity, plugs[commodity], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package accounting
+
+import (
+	"strconv"
+	"time"
+)
+
+// CommodityINR is the cash commodity; every other commodity is a stock
+// symbol counted in units
+const CommodityINR = "INR"
+
+// Account types. Assets and expenses carry debit (positive) balances,
+// liabilities, equity and income credit (negative) ones.
+const (
+	TypeAsset     = "asset"
+	TypeLiability = "liability"
+	TypeEquity    = "equity"
+	TypeIncome    = "income"
+	TypeExpense   = "expense"
+)
+
+// Chart of accounts. User holdings get one account per user, created on
+// first use; the rest are seeded with the schema.
+const (
+	AccountCash           = "assets:cash"
+	AccountStockInventory = "assets:stock_inventory"
+	AccountTaxPayable     = "liabilities:tax_payable"
+	AccountRewards        = "expenses:rewards"
+	AccountBrokerage      = "expenses:fees:brokerage"
+	AccountOtherFees      = "expenses:fees:other"
+	AccountSTT            = "expenses:taxes:stt"
+	AccountGST            = "expenses:taxes:gst"
+	AccountRounding       = "equity:rounding"
+
+	userHoldingsPrefix = "liabilities:user_holdings:"
+)
+
+// UserHoldings is the account for shares the company holds on behalf of a user
+func UserHoldings(userID int) string {
+	return userHoldingsPrefix + strconv.Itoa(userID)
+}
+
+// Journal kinds
+const (
+	KindPurchase      = "purchase"
+	KindReversal      = "reversal"
+	KindSettlement    = "settlement"
+	KindFeeAdjustment = "fee_adjustment"
+)
+
+type Account struct {
+	ID        int       `db:"id" json:"id"`
+	Code      string    `db:"code" json:"code"`
+	Name      string    `db:"name" json:"name"`
+	Type      string    `db:"type" json:"type"`
+	UserID    *int      `db:"user_id" json:"user_id,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// Line is one posting; Amount is positive for a debit, negative for a credit
+type Line struct {
+	Account   string  `db:"account" json:"account"`
+	Commodity string  `db:"commodity" json:"commodity"`
+	Amount    float64 `db:"amount" json:"amount"`
+}
+
+type Journal struct {
+	ID            int       `db:"id" json:"id"`
+	Kind          string    `db:"kind" json:"kind"`
+	RewardID      *int      `db:"reward_id" json:"reward_id"`
+	LedgerEntryID *int      `db:"ledger_entry_id" json:"ledger_entry_id"`
+	Description   string    `db:"description" json:"description"`
+	PostedAt      time.Time `db:"posted_at" json:"posted_at"`
+	Lines         []Line    `db:"-" json:"lines"`
+}
+
+// Imbalance is a journal whose lines do not sum to zero in a commodity
+type Imbalance struct {
+	JournalID int     `db:"journal_id" json:"journal_id"`
+	Commodity string  `db:"commodity" json:"commodity"`
+	Amount    float64 `db:"amount" json:"amount"`
+}
+
+// CheckResult reports every ledger invariant that does not hold
+type CheckResult struct {
+	OK                bool            `json:"ok"`
+	Journals          int             `json:"journals"`
+	Lines             int             `json:"lines"`
+	Unbalanced        []Imbalance     `json:"unbalanced"`
+	SingleLine        []int           `json:"single_line"`     // journals with fewer than two lines
+	UnpostedLedger    []int           `json:"unposted_ledger"` // ledger_entries without a journal
+	InventoryMismatch []UnitsMismatch `json:"inventory_mismatch"`
+}
+
+// UnitsMismatch is a symbol whose stock inventory balance differs from
+// the units recorded in ledger_entries
+type UnitsMismatch struct {
+	Symbol    string  `db:"symbol" json:"symbol"`
+	Inventory float64 `db:"inventory" json:"inventory"`
+	Ledger    float64 `db:"ledger" json:"ledger"`
+}
+
+// TrialBalanceLine is an account's balance in one commodity. Stock
+// balances are valued in INR at the last stored close on or before the
+// as-of date; Debit and Credit are that INR value.
+type TrialBalanceLine struct {
+	Account   string     `db:"account" json:"account"`
+	Type      string     `db:"type" json:"type"`
+	Commodity string     `db:"commodity" json:"commodity"`
+	Balance   float64    `db:"balance" json:"balance"` // in the commodity, signed
+	Price     float64    `db:"-" json:"price,omitempty"`
+	PriceAsOf *time.Time `db:"-" json:"price_as_of,omitempty"`
+	Debit     float64    `db:"-" json:"debit"`
+	Credit    float64    `db:"-" json:"credit"`
+}
+
+type TrialBalance struct {
+	AsOf        string             `json:"as_of"` // IST date, inclusive
+	Lines       []TrialBalanceLine `json:"lines"`
+	TotalDebit  float64            `json:"total_debit"`
+	TotalCredit float64            `json:"total_credit"`
+	Balanced    bool               `json:"balanced"`
+	Unpriced    []string           `json:"unpriced"` // symbols with no stored price, valued at 0
+}
+
+// BalanceSheetLine shows an amount with its normal sign: assets as
+// debits, liabilities and equity as credits
+type BalanceSheetLine struct {
+	Account   string  `json:"account"`
+	Commodity string  `json:"commodity"`
+	Units     float64 `json:"units,omitempty"`
+	Value     float64 `json:"value"`
+}
+
+type BalanceSheet struct {
+	AsOf             string             `json:"as_of"`
+	Assets           []BalanceSheetLine `json:"assets"`
+	Liabilities      []BalanceSheetLine `json:"liabilities"`
+	Equity           []BalanceSheetLine `json:"equity"`
+	TotalAssets      float64            `json:"total_assets"`
+	TotalLiabilities float64            `json:"total_liabilities"`
+	TotalEquity      float64            `json:"total_equity"`
+	Balanced         bool               `json:"balanced"`
+	Unpriced         []string           `json:"unpriced"`
+}
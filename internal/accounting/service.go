@@ -0,0 +1,94 @@
+package accounting
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AccountingService struct {
+	db *sqlx.DB
+}
+
+func NewAccountingService(db *sqlx.DB) *AccountingService {
+	return &AccountingService{db: db}
+}
+
+// Check verifies the ledger invariants over every journal: each journal
+// balances per commodity and has at least two lines, every ledger entry
+// was posted, and stock inventory matches the units in ledger_entries
+func (s *AccountingService) Check(ctx context.Context) (CheckResult, error) {
+	result := CheckResult{
+		Unbalanced:        []Imbalance{},
+		SingleLine:        []int{},
+		UnpostedLedger:    []int{},
+		InventoryMismatch: []UnitsMismatch{},
+	}
+
+	err := s.db.QueryRowxContext(ctx, `
+		SELECT (SELECT COUNT(*) FROM journals), (SELECT COUNT(*) FROM journal_lines)
+	`).Scan(&result.Journals, &result.Lines)
+	if err != nil {
+		return result, err
+	}
+
+	err = s.db.SelectContext(ctx, &result.Unbalanced, `
+		SELECT journal_id, commodity, SUM(amount) AS amount
+		FROM journal_lines
+		GROUP BY journal_id, commodity
+		HAVING SUM(amount) <> 0
+		ORDER BY journal_id, commodity
+	`)
+	if err != nil {
+		return result, err
+	}
+
+	err = s.db.SelectContext(ctx, &result.SingleLine, `
+		SELECT j.id
+		FROM journals j
+		LEFT JOIN journal_lines l ON l.journal_id = j.id
+		GROUP BY j.id
+		HAVING COUNT(l.id) < 2
+		ORDER BY j.id
+	`)
+	if err != nil {
+		return result, err
+	}
+
+	err = s.db.SelectContext(ctx, &result.UnpostedLedger, `
+		SELECT e.id
+		FROM ledger_entries e
+		WHERE NOT EXISTS (SELECT 1 FROM journals j WHERE j.ledger_entry_id = e.id)
+		ORDER BY e.id
+	`)
+	if err != nil {
+		return result, err
+	}
+
+	err = s.db.SelectContext(ctx, &result.InventoryMismatch, `
+		WITH inventory AS (
+			SELECT l.commodity AS symbol, SUM(l.amount) AS units
+			FROM journal_lines l
+			JOIN accounts a ON a.id = l.account_id
+			WHERE a.code = $1
+			GROUP BY l.commodity
+		), ledger AS (
+			SELECT stock_symbol AS symbol, SUM(stock_units) AS units
+			FROM ledger_entries
+			GROUP BY stock_symbol
+		)
+		SELECT COALESCE(i.symbol, g.symbol) AS symbol,
+		       COALESCE(i.units, 0) AS inventory, COALESCE(g.units, 0) AS ledger
+		FROM inventory i
+		FULL JOIN ledger g ON g.symbol = i.symbol
+		WHERE COALESCE(i.units, 0) <> COALESCE(g.units, 0)
+		ORDER BY 1
+	`, AccountStockInventory)
+	if err != nil {
+		return result, err
+	}
+
+	result.OK = len(result.Unbalanced) == 0 && len(result.SingleLine) == 0 &&
+		len(result.UnpostedLedger) == 0 && len(result.InventoryMismatch) == 0
+	return result, nil
+}
@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Audited actions
+const (
+	ActionRewardIssued        = "reward.issued"
+	ActionRewardStatusChanged = "reward.status_changed"
+	ActionRewardReversed      = "reward.reversed"
+	ActionRewardExpired       = "reward.kyc_expired"
+	ActionApprovalRequested   = "reward_approval.requested"
+	ActionApprovalApproved    = "reward_approval.approved"
+	ActionApprovalRejected    = "reward_approval.rejected"
+	ActionFeeDiffApproved     = "fee_difference.approved"
+	ActionFeeDiffRejected     = "fee_difference.rejected"
+	ActionRolesChanged        = "user.roles_changed"
+	ActionAPIKeyCreated       = "api_key.created"
+	ActionAPIKeyRevoked       = "api_key.revoked"
+	ActionInstrumentCreated   = "instrument.created"
+	ActionInstrumentUpdated   = "instrument.updated"
+	ActionUserUpdated         = "user.updated"
+	ActionUserDeactivated     = "user.deactivated"
+	ActionUserReactivated     = "user.reactivated"
+	ActionKYCChanged          = "user.kyc_changed"
+)
+
+// genesisHash is the previous hash of the first entry
+var genesisHash = strings.Repeat("0", 64)
+
+// Actor identifies who caused an audited change. It travels on the request
+// context, set by the server's middleware.
+type Actor struct {
+	UserID    int
+	APIKeyID  int
+	IP        string
+	RequestID string
+}
+
+type actorKey struct{}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the context's actor; background jobs have none
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// Entry describes one audited change. Before and After are marshalled to
+// JSON; leave Before nil for creations.
+type Entry struct {
+	Action     string
+	EntityType string
+	EntityID   string
+	Before     interface{}
+	After      interface{}
+}
+
+// Tx is a transaction that can record audit entries. Its Commit appends
+// the recorded entries to the chain before committing and its Rollback
+// discards them, so an entry exists exactly when the change it describes
+// does. Helpers that only read or write rows take the embedded *sqlx.Tx.
+type Tx struct {
+	*sqlx.Tx
+	ctx     context.Context
+	entries []Log
+}
+
+// Begin starts an audited transaction. ctx is used for the statements
+// Commit runs, as BeginTxx uses it for the transaction itself.
+func Begin(ctx context.Context, db *sqlx.DB) (*Tx, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, ctx: ctx}, nil
+}
+
+// Record queues an entry on the transaction; it is written by Commit
+func Record(ctx context.Context, tx *Tx, e Entry) error {
+	before, err := json.Marshal(e.Before)
+	if err != nil {
+		return err
+	}
+	after, err := json.Marshal(e.After)
+	if err != nil {
+		return err
+	}
+
+	actor := ActorFrom(ctx)
+	tx.entries = append(tx.entries, Log{
+		ActorUserID:   nullableID(actor.UserID),
+		ActorAPIKeyID: nullableID(actor.APIKeyID),
+		ActorIP:       actor.IP,
+		RequestID:     actor.RequestID,
+		Action:        e.Action,
+		EntityType:    e.EntityType,
+		EntityID:      e.EntityID,
+		Before:        before,
+		After:         after,
+	})
+	return nil
+}
+
+// Commit appends the recorded entries to the chain and commits. The chain
+// head row is locked only here, as the last work in the transaction, so
+// audited writes are serialised for a few statements rather than for
+// their whole duration. Entries are stamped with the database clock once
+// the lock is held, so the chain is in occurred_at order across instances.
+func (t *Tx) Commit() error {
+	entries := t.entries
+	t.entries = nil
+	if len(entries) == 0 {
+		return t.Tx.Commit()
+	}
+
+	var prev string
+	if err := t.GetContext(t.ctx, &prev, `SELECT hash FROM audit_chain_head WHERE id = 1 FOR UPDATE`); err != nil {
+		return err
+	}
+	var now time.Time
+	if err := t.GetContext(t.ctx, &now, `SELECT clock_timestamp()`); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		entry.OccurredAt = now.UTC()
+		entry.PrevHash = prev
+		entry.Hash = entry.computeHash()
+		_, err := t.ExecContext(t.ctx, `
+			INSERT INTO audit_log (occurred_at, actor_user_id, actor_api_key_id, actor_ip, request_id,
+			                       action, entity_type, entity_id, before_value, after_value, prev_hash, hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`, entry.OccurredAt, entry.ActorUserID, entry.ActorAPIKeyID, entry.ActorIP, entry.RequestID,
+			entry.Action, entry.EntityType, entry.EntityID, string(entry.Before), string(entry.After),
+			entry.PrevHash, entry.Hash,
+		)
+		if err != nil {
+			return err
+		}
+		prev = entry.Hash
+	}
+
+	if _, err := t.ExecContext(t.ctx, `UPDATE audit_chain_head SET hash = $1 WHERE id = 1`, prev); err != nil {
+		return err
+	}
+	return t.Tx.Commit()
+}
+
+// Rollback discards the recorded entries and rolls back. Like sqlx's
+// Rollback it is safe to defer after Commit.
+func (t *Tx) Rollback() error {
+	t.entries = nil
+	return t.Tx.Rollback()
+}
+
+// computeHash covers every stored field and the previous entry's hash, so
+// editing any entry or removing one from the middle breaks the chain
+func (l Log) computeHash() string {
+	fields, _ := json.Marshal([]interface{}{
+		l.PrevHash,
+		l.OccurredAt.UTC().Format(time.RFC3339Nano),
+		l.ActorUserID,
+		l.ActorAPIKeyID,
+		l.ActorIP,
+		l.RequestID,
+		l.Action,
+		l.EntityType,
+		l.EntityID,
+		string(l.Before),
+		string(l.After),
+	})
+	sum := sha256.Sum256(fields)
+	return hex.EncodeToString(sum[:])
+}
+
+func nullableID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
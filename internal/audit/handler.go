@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	service *AuditService
+}
+
+func NewAuditHandler(service *AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// ListAudit handles GET /admin/audit?actor_user_id=&action=&entity_type=
+// &entity_id=&request_id=&from=&to=&before_id=&limit=50
+func (h *AuditHandler) ListAudit(c *gin.Context) {
+	f := Filter{
+		Action:     c.Query("action"),
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		RequestID:  c.Query("request_id"),
+	}
+
+	var err error
+	f.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || f.Limit <= 0 || f.Limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	if v := c.Query("actor_user_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid actor_user_id"})
+			return
+		}
+		f.ActorUserID = &id
+	}
+	if v := c.Query("before_id"); v != "" {
+		f.BeforeID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before_id"})
+			return
+		}
+	}
+	for param, dst := range map[string]**time.Time{"from": &f.From, "to": &f.To} {
+		if v := c.Query(param); v != "" {
+			t, err := parseTime(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + ", use YYYY-MM-DD or RFC 3339"})
+				return
+			}
+			*dst = &t
+		}
+	}
+
+	logs, err := h.service.List(context.Background(), f)
+	if err != nil {
+		logger.Log.Errorf("Failed to list audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": logs})
+}
+
+// VerifyAudit handles GET /admin/audit/verify
+func (h *AuditHandler) VerifyAudit(c *gin.Context) {
+	result, err := h.service.Verify(context.Background())
+	if err != nil {
+		logger.Log.Errorf("Failed to verify audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify audit log"})
+		return
+	}
+	if !result.Valid {
+		logger.Log.WithField("broken_at", result.BrokenAt).Error("Audit log chain is broken: " + result.Reason)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// parseTime accepts a date, read as midnight IST, or an RFC 3339 time
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, calendar.IST)
+}
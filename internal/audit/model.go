@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Log struct {
+	ID            int64           `db:"id" json:"id"`
+	OccurredAt    time.Time       `db:"occurred_at" json:"occurred_at"`
+	ActorUserID   *int            `db:"actor_user_id" json:"actor_user_id"`
+	ActorAPIKeyID *int            `db:"actor_api_key_id" json:"actor_api_key_id"`
+	ActorIP       string          `db:"actor_ip" json:"actor_ip"`
+	RequestID     string          `db:"request_id" json:"request_id"`
+	Action        string          `db:"action" json:"action"`
+	EntityType    string          `db:"entity_type" json:"entity_type"`
+	EntityID      string          `db:"entity_id" json:"entity_id"`
+	Before        json.RawMessage `db:"before_value" json:"before"`
+	After         json.RawMessage `db:"after_value" json:"after"`
+	PrevHash      string          `db:"prev_hash" json:"prev_hash"`
+	Hash          string          `db:"hash" json:"hash"`
+}
+
+const logColumns = `id, occurred_at, actor_user_id, actor_api_key_id, actor_ip, request_id,
+	action, entity_type, entity_id, before_value, after_value, prev_hash, hash`
+
+// Filter narrows GET /admin/audit; zero values match everything
+type Filter struct {
+	ActorUserID *int
+	Action      string
+	EntityType  string
+	EntityID    string
+	RequestID   string
+	From        *time.Time
+	To          *time.Time
+	BeforeID    int64
+	Limit       int
+}
+
+// VerifyResult reports whether the chain is intact. HeadHash should be
+// noted somewhere outside the database: the chain alone cannot show that
+// entries were removed from its end.
+type VerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Checked  int    `json:"checked"`
+	HeadID   int64  `json:"head_id"`
+	HeadHash string `json:"head_hash"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
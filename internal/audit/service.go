@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditService struct {
+	db *sqlx.DB
+}
+
+func NewAuditService(db *sqlx.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// List returns entries newest first
+func (s *AuditService) List(ctx context.Context, f Filter) ([]Log, error) {
+	logs := []Log{}
+	err := s.db.SelectContext(ctx, &logs, `
+		SELECT `+logColumns+`
+		FROM audit_log
+		WHERE ($1::int IS NULL OR actor_user_id = $1)
+		  AND ($2 = '' OR action = $2)
+		  AND ($3 = '' OR entity_type = $3)
+		  AND ($4 = '' OR entity_id = $4)
+		  AND ($5 = '' OR request_id = $5)
+		  AND ($6::timestamptz IS NULL OR occurred_at >= $6)
+		  AND ($7::timestamptz IS NULL OR occurred_at < $7)
+		  AND ($8 = 0 OR id < $8)
+		ORDER BY id DESC
+		LIMIT $9
+	`, f.ActorUserID, f.Action, f.EntityType, f.EntityID, f.RequestID, f.From, f.To, f.BeforeID, f.Limit)
+	return logs, err
+}
+
+// Verify walks the whole chain in order, recomputing every hash
+func (s *AuditService) Verify(ctx context.Context) (VerifyResult, error) {
+	result := VerifyResult{Valid: true, HeadHash: genesisHash}
+
+	rows, err := s.db.QueryxContext(ctx, `SELECT `+logColumns+` FROM audit_log ORDER BY id`)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry Log
+		if err := rows.StructScan(&entry); err != nil {
+			return result, err
+		}
+		result.Checked++
+
+		switch {
+		case entry.PrevHash != result.HeadHash:
+			result.Reason = fmt.Sprintf("entry %d does not link to the entry before it; an entry was removed or edited", entry.ID)
+		case entry.computeHash() != entry.Hash:
+			result.Reason = fmt.Sprintf("entry %d does not match its hash; it was edited", entry.ID)
+		}
+		if result.Reason != "" {
+			result.Valid = false
+			result.BrokenAt = entry.ID
+			return result, nil
+		}
+
+		result.HeadID = entry.ID
+		result.HeadHash = entry.Hash
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/lib/pq"
+)
+
+const apiKeyPrefix = "sk_"
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type APIKey struct {
+	ID               int            `db:"id" json:"id"`
+	Name             string         `db:"name" json:"name"`
+	Prefix           string         `db:"prefix" json:"prefix"`
+	Roles            pq.StringArray `db:"roles" json:"roles"`
+	AllowedCampaigns pq.StringArray `db:"allowed_campaigns" json:"allowed_campaigns"`
+	MaxQuantity      float64        `db:"max_quantity" json:"max_quantity"`
+	CreatedAt        time.Time      `db:"created_at" json:"created_at"`
+	LastUsedAt       *time.Time     `db:"last_used_at" json:"last_used_at"`
+	RevokedAt        *time.Time     `db:"revoked_at" json:"revoked_at"`
+}
+
+// CreatedAPIKey carries the plaintext key, which is only shown once
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type APIKeyRequest struct {
+	Name             string   `json:"name" binding:"required"`
+	Roles            []string `json:"roles" binding:"required"`
+	AllowedCampaigns []string `json:"allowed_campaigns"`
+	MaxQuantity      float64  `json:"max_quantity"` // 0 = no limit
+}
+
+const apiKeyColumns = `id, name, prefix, roles, allowed_campaigns, max_quantity, created_at, last_used_at, revoked_at`
+
+// CreateAPIKey issues a key for a server-to-server caller. Only its SHA-256
+// hash is stored; the short prefix identifies it in listings and logs.
+func (s *AuthService) CreateAPIKey(ctx context.Context, req APIKeyRequest) (CreatedAPIKey, error) {
+	var created CreatedAPIKey
+
+	for _, r := range req.Roles {
+		if !validRoles[r] {
+			return created, fmt.Errorf("%w: %s", ErrUnknownRole, r)
+		}
+	}
+	if req.MaxQuantity < 0 {
+		return created, fmt.Errorf("max_quantity must not be negative")
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return created, err
+	}
+	secret := hex.EncodeToString(buf)
+	prefix := secret[:8]
+	created.Key = apiKeyPrefix + secret
+
+	campaigns := req.AllowedCampaigns
+	if campaigns == nil {
+		campaigns = []string{}
+	}
+
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return created, err
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &created.APIKey, `
+		INSERT INTO api_keys (name, prefix, key_hash, roles, allowed_campaigns, max_quantity)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+apiKeyColumns,
+		req.Name, prefix, hashToken(created.Key), pq.StringArray(req.Roles),
+		pq.StringArray(campaigns), req.MaxQuantity,
+	)
+	if err != nil {
+		return created, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{
+		Action:     audit.ActionAPIKeyCreated,
+		EntityType: "api_key",
+		EntityID:   strconv.Itoa(created.ID),
+		After:      created.APIKey,
+	})
+	if err != nil {
+		return created, err
+	}
+	return created, tx.Commit()
+}
+
+func (s *AuthService) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	keys := []APIKey{}
+	err := s.db.SelectContext(ctx, &keys, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	return keys, err
+}
+
+func (s *AuthService) RevokeAPIKey(ctx context.Context, id int) error {
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var key APIKey
+	err = tx.GetContext(ctx, &key, `
+		UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1
+		RETURNING `+apiKeyColumns,
+		id,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{
+		Action:     audit.ActionAPIKeyRevoked,
+		EntityType: "api_key",
+		EntityID:   strconv.Itoa(id),
+		After:      key,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// authenticateAPIKey resolves a presented key to its Principal
+func (s *AuthService) authenticateAPIKey(ctx context.Context, raw string) (Principal, error) {
+	var key APIKey
+	err := s.db.GetContext(ctx, &key, `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE key_hash = $1 AND revoked_at IS NULL
+		RETURNING `+apiKeyColumns,
+		hashToken(raw),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Principal{}, ErrInvalidToken
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+
+	return Principal{
+		APIKeyID:         key.ID,
+		Roles:            key.Roles,
+		AllowedCampaigns: key.AllowedCampaigns,
+		MaxQuantity:      key.MaxQuantity,
+	}, nil
+}
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type AuthHandler struct {
+	service *AuthService
+}
+
+func NewAuthHandler(service *AuthService) *AuthHandler {
+	return &AuthHandler{service: service}
+}
+
+// Login handles POST /auth/login
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	pair, err := h.service.Login(context.Background(), req.UserID, req.Password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		logger.Log.WithField("user_id", req.UserID).Warn("Failed login attempt")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Login failed for user %d: %v", req.UserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log in"})
+		return
+	}
+
+	logger.Log.WithField("user_id", req.UserID).Info("User logged in")
+	c.JSON(http.StatusOK, pair)
+}
+
+// Refresh handles POST /auth/refresh
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	pair, err := h.service.Refresh(context.Background(), req.RefreshToken)
+	if errors.Is(err, ErrInvalidToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Token refresh failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pair)
+}
+
+// Logout handles POST /auth/logout
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.service.Logout(context.Background(), req.RefreshToken); err != nil {
+		logger.Log.Errorf("Logout failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetUserRoles handles GET /admin/users/:id/roles
+func (h *AuthHandler) GetUserRoles(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	roles, err := h.service.GetRoles(context.Background(), userID)
+	if err != nil {
+		logger.Log.Errorf("Failed to fetch roles for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "roles": roles})
+}
+
+// SetUserRoles handles PUT /admin/users/:id/roles
+func (h *AuthHandler) SetUserRoles(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req RolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	roles, err := h.service.SetRoles(c.Request.Context(), userID, req.Roles)
+	if errors.Is(err, ErrUnknownRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Failed to set roles for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set roles"})
+		return
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"user_id": userID,
+		"roles":   roles,
+	}).Info("User roles updated")
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "roles": roles})
+}
+
+// CreateAPIKey handles POST /admin/api-keys. The key is only returned here.
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var req APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(c.Request.Context(), req)
+	if err != nil {
+		logger.Log.Warnf("Failed to create API key: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"api_key_id": key.ID,
+		"prefix":     key.Prefix,
+	}).Info("API key created")
+	c.JSON(http.StatusCreated, key)
+}
+
+func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.service.ListAPIKeys(context.Background())
+	if err != nil {
+		logger.Log.Errorf("Failed to list API keys: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list api keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// RevokeAPIKey handles DELETE /admin/api-keys/:id
+func (h *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid api key id"})
+		return
+	}
+
+	err = h.service.RevokeAPIKey(c.Request.Context(), id)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Failed to revoke API key %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke api key"})
+		return
+	}
+
+	logger.Log.WithField("api_key_id", id).Info("API key revoked")
+	c.Status(http.StatusNoContent)
+}
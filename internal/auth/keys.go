@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// KeyRing holds the HMAC keys tokens may be signed with. Tokens are signed
+// with the active key and verified with whichever key their "kid" names, so
+// a new key can be introduced and made active while tokens signed with the
+// previous one stay valid until they expire.
+type KeyRing struct {
+	active string
+	keys   map[string][]byte
+}
+
+// ParseKeyRing reads "kid1:secret1,kid2:secret2". An empty spec produces a
+// random ephemeral key, which invalidates all tokens on restart.
+func ParseKeyRing(spec, active string) (*KeyRing, bool, error) {
+	ring := &KeyRing{keys: map[string][]byte{}}
+
+	if strings.TrimSpace(spec) == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, false, err
+		}
+		ring.keys["ephemeral"] = []byte(hex.EncodeToString(buf))
+		ring.active = "ephemeral"
+		return ring, true, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		kid, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || kid == "" || len(secret) < 32 {
+			return nil, false, fmt.Errorf("invalid JWT key %q: want kid:secret with a secret of at least 32 chars", kid)
+		}
+		ring.keys[kid] = []byte(secret)
+		if ring.active == "" {
+			ring.active = kid
+		}
+	}
+
+	if active != "" {
+		if _, ok := ring.keys[active]; !ok {
+			return nil, false, fmt.Errorf("active JWT key %q is not configured", active)
+		}
+		ring.active = active
+	}
+	return ring, false, nil
+}
+
+func (r *KeyRing) signingKey() (string, []byte) {
+	return r.active, r.keys[r.active]
+}
+
+func (r *KeyRing) lookup(kid string) ([]byte, bool) {
+	key, ok := r.keys[kid]
+	return key, ok
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+var (
+	secretA = strings.Repeat("a", 32)
+	secretB = strings.Repeat("b", 40)
+)
+
+func TestParseKeyRingActiveKey(t *testing.T) {
+	ring, ephemeral, err := ParseKeyRing("k1:"+secretA+", k2:"+secretB, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ephemeral {
+		t.Error("configured ring reported as ephemeral")
+	}
+	if kid, key := ring.signingKey(); kid != "k1" || string(key) != secretA {
+		t.Errorf("signing with %s, want k1 by default", kid)
+	}
+	// Retired keys still verify tokens issued before a rotation.
+	if key, ok := ring.lookup("k2"); !ok || string(key) != secretB {
+		t.Error("k2 not available for verification")
+	}
+
+	ring, _, err = ParseKeyRing("k1:"+secretA+",k2:"+secretB, "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid, _ := ring.signingKey(); kid != "k2" {
+		t.Errorf("signing with %s, want k2", kid)
+	}
+}
+
+func TestParseKeyRingRejectsBadSpecs(t *testing.T) {
+	bad := map[string][2]string{
+		"unknown active key": {"k1:" + secretA, "k2"},
+		"short secret":       {"k1:short", ""},
+		"missing kid":        {":" + secretA, ""},
+		"missing secret":     {"k1", ""},
+	}
+	for name, args := range bad {
+		if _, _, err := ParseKeyRing(args[0], args[1]); err == nil {
+			t.Errorf("%s: ParseKeyRing(%q, %q) succeeded", name, args[0], args[1])
+		}
+	}
+}
+
+func TestParseKeyRingEphemeral(t *testing.T) {
+	ring, ephemeral, err := ParseKeyRing(" ", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ephemeral {
+		t.Error("empty spec should produce an ephemeral key")
+	}
+	kid, key := ring.signingKey()
+	if kid != "ephemeral" || len(key) != 64 {
+		t.Errorf("signing key = %s (%d bytes), want a 64 char ephemeral key", kid, len(key))
+	}
+
+	other, _, _ := ParseKeyRing("", "")
+	if _, otherKey := other.signingKey(); string(otherKey) == string(key) {
+		t.Error("ephemeral keys repeat")
+	}
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const principalKey = "auth.principal"
+
+// APIKeyHeader carries server-to-server API keys
+const APIKeyHeader = "X-API-Key"
+
+// Identify resolves the caller from a Bearer token or API key when one is
+// valid, without rejecting anonymous requests. It runs globally so that
+// middleware such as rate limiting can see who is calling; RequireAuth
+// then reuses the result.
+func (s *AuthService) Identify() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal, err := s.authenticate(c); err == nil {
+			c.Set(principalKey, principal)
+		}
+		c.Next()
+	}
+}
+
+// RequireAuth rejects requests without a valid Bearer access token or API
+// key and stores the caller's Principal on the context
+func (s *AuthService) RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := FromContext(c); ok {
+			c.Next()
+			return
+		}
+
+		principal, err := s.authenticate(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+var (
+	errMissingToken  = errors.New("missing bearer token")
+	errInvalidAPIKey = errors.New("invalid api key")
+)
+
+func (s *AuthService) authenticate(c *gin.Context) (Principal, error) {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		principal, err := s.authenticateAPIKey(c.Request.Context(), key)
+		if err != nil {
+			return Principal{}, errInvalidAPIKey
+		}
+		return principal, nil
+	}
+
+	header := c.GetHeader("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return Principal{}, errMissingToken
+	}
+	return s.ParseAccessToken(token)
+}
+
+// RequireSelf only lets callers reach routes whose path parameter names
+// their own user ID. Admins may reach any user, and support staff may
+// read any user but not change anything. It must run after RequireAuth.
+func RequireSelf(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := FromContext(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		userID, err := strconv.Atoi(c.Param(param))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+		readOnly := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+		if userID != principal.UserID && !principal.HasRole(RoleAdmin) &&
+			!(readOnly && principal.HasRole(RoleSupport)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access to another user's data is not allowed"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// FromContext returns the authenticated caller, if any
+func FromContext(c *gin.Context) (Principal, bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	principal, ok := v.(Principal)
+	return principal, ok
+}
@@ -0,0 +1,40 @@
+package auth
+
+import "github.com/golang-jwt/jwt/v5"
+
+// Claims carried in an access token. The subject is the user ID.
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+}
+
+type LoginRequest struct {
+	UserID   int    `json:"user_id" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // access token lifetime in seconds
+}
+
+// Principal is the authenticated caller of a request: either a user
+// holding an access token, or a server-to-server API key (UserID 0)
+type Principal struct {
+	UserID int
+	Roles  []string
+
+	APIKeyID         int
+	AllowedCampaigns []string
+	MaxQuantity      float64
+}
+
+type RolesRequest struct {
+	Roles []string `json:"roles" binding:"required"`
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+	RoleFinance = "finance"
+	RolePartner = "partner"
+)
+
+var validRoles = map[string]bool{
+	RoleUser: true, RoleSupport: true, RoleAdmin: true, RoleFinance: true, RolePartner: true,
+}
+
+// HasRole reports whether the caller holds any of the given roles
+func (p Principal) HasRole(roles ...string) bool {
+	for _, have := range p.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllowsIssuance checks an API key's campaign and quantity limits. Callers
+// authenticated with a user token are limited only by their roles.
+func (p Principal) AllowsIssuance(campaign string, quantity float64) error {
+	if p.APIKeyID == 0 {
+		return nil
+	}
+	if len(p.AllowedCampaigns) > 0 {
+		allowed := false
+		for _, c := range p.AllowedCampaigns {
+			if c == campaign {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("campaign %q is not allowed for this API key", campaign)
+		}
+	}
+	if p.MaxQuantity > 0 && quantity > p.MaxQuantity {
+		return fmt.Errorf("quantity %v exceeds this API key's limit of %v", quantity, p.MaxQuantity)
+	}
+	return nil
+}
+
+// RequireRole only admits callers holding one of the roles. It must run
+// after RequireAuth.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := FromContext(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+		if !principal.HasRole(roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "requires one of roles: " + strings.Join(roles, ", "),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetRoles returns a user's roles. Every user implicitly has RoleUser.
+func (s *AuthService) GetRoles(ctx context.Context, userID int) ([]string, error) {
+	roles := []string{}
+	err := s.db.SelectContext(ctx, &roles, `
+		SELECT role FROM user_roles WHERE user_id = $1 AND role <> $2 ORDER BY role
+	`, userID, RoleUser)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{RoleUser}, roles...), nil
+}
+
+// SetRoles replaces a user's granted roles. Changes reach the user's
+// access tokens on their next refresh.
+func (s *AuthService) SetRoles(ctx context.Context, userID int, roles []string) ([]string, error) {
+	for _, r := range roles {
+		if !validRoles[r] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRole, r)
+		}
+	}
+
+	before, err := s.GetRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_roles WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_roles (user_id, role)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING
+	`, userID, pq.StringArray(roles))
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{
+		Action:     audit.ActionRolesChanged,
+		EntityType: "user",
+		EntityID:   strconv.Itoa(userID),
+		Before:     before,
+		After:      roles,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetRoles(ctx, userID)
+}
+
+// BootstrapAdmins grants the admin role to the comma-separated user IDs so
+// a fresh deployment has someone able to manage roles and keys
+func (s *AuthService) BootstrapAdmins(ctx context.Context, userIDs string) error {
+	for _, raw := range strings.Split(userIDs, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid admin user id %q", raw)
+		}
+		_, err = s.db.ExecContext(ctx, `
+			INSERT INTO user_roles (user_id, role)
+			SELECT id, $2 FROM users WHERE id = $1
+			ON CONFLICT DO NOTHING
+		`, id, RoleAdmin)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const issuer = "stocky"
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrUnknownRole        = errors.New("unknown role")
+)
+
+// AuthService issues and verifies access tokens and refresh tokens
+type AuthService struct {
+	db         *sqlx.DB
+	keys       *KeyRing
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewAuthService(db *sqlx.DB, keys *KeyRing, accessTTL, refreshTTL time.Duration) *AuthService {
+	return &AuthService{db: db, keys: keys, accessTTL: accessTTL, refreshTTL: refreshTTL}
+}
+
+// HashPassword returns the bcrypt hash stored for a user's password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+func (s *AuthService) Login(ctx context.Context, userID int, password string) (TokenPair, error) {
+	var hash sql.NullString
+	err := s.db.GetContext(ctx, &hash,
+		`SELECT password_hash FROM users WHERE id = $1 AND status = 'active'`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TokenPair{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if !hash.Valid || bcrypt.CompareHashAndPassword([]byte(hash.String), []byte(password)) != nil {
+		return TokenPair{}, ErrInvalidCredentials
+	}
+
+	return s.issue(ctx, s.db, userID)
+}
+
+// Refresh exchanges a refresh token for a new pair. Refresh tokens are
+// single-use: presenting one that was already rotated revokes every
+// refresh token of the user, since it means the token leaked.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (TokenPair, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	defer tx.Rollback()
+
+	var stored struct {
+		ID        int          `db:"id"`
+		UserID    int          `db:"user_id"`
+		ExpiresAt time.Time    `db:"expires_at"`
+		RevokedAt sql.NullTime `db:"revoked_at"`
+	}
+	err = tx.GetContext(ctx, &stored, `
+		SELECT id, user_id, expires_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`, hashToken(refreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return TokenPair{}, ErrInvalidToken
+	}
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	if stored.RevokedAt.Valid {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL
+		`, stored.UserID); err != nil {
+			return TokenPair{}, err
+		}
+		if err := tx.Commit(); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, ErrInvalidToken
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return TokenPair{}, ErrInvalidToken
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = $1
+	`, stored.ID); err != nil {
+		return TokenPair{}, err
+	}
+
+	pair, err := s.issue(ctx, tx, stored.UserID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return pair, tx.Commit()
+}
+
+// Logout revokes a refresh token. Unknown tokens are ignored.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW() WHERE token_hash = $1 AND revoked_at IS NULL
+	`, hashToken(refreshToken))
+	return err
+}
+
+// ParseAccessToken validates a token's signature, issuer and expiry
+func (s *AuthService) ParseAccessToken(tokenString string) (Principal, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := s.keys.lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	return Principal{UserID: userID, Roles: claims.Roles}, nil
+}
+
+func (s *AuthService) issue(ctx context.Context, db sqlx.ExecerContext, userID int) (TokenPair, error) {
+	now := time.Now()
+	kid, key := s.keys.signingKey()
+
+	roles, err := s.GetRoles(ctx, userID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL)),
+		},
+		Roles: roles,
+	})
+	token.Header["kid"] = kid
+	access, err := token.SignedString(key)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return TokenPair{}, err
+	}
+	refresh := hex.EncodeToString(buf)
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)
+	`, userID, hashToken(refresh), now.Add(s.refreshTTL))
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.accessTTL.Seconds()),
+	}, nil
+}
+
+// hashToken stores refresh tokens as SHA-256 so a database leak does not
+// expose usable tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,34 @@
+package broker
+
+import (
+	"context"
+	"time"
+)
+
+// Order is an aggregated buy for one symbol submitted to a broker
+type Order struct {
+	ID       int
+	Symbol   string
+	Quantity float64
+}
+
+// Execution is the broker's fill report for an order. FilledQuantity may be
+// less than requested; the remainder is retried in a later batch.
+type Execution struct {
+	BrokerRef      string    `db:"broker_ref"`
+	FilledQuantity float64   `db:"filled_quantity"`
+	AvgPrice       float64   `db:"avg_price"`
+	Brokerage      float64   `db:"brokerage_fee"`
+	STT            float64   `db:"stt"`
+	GST            float64   `db:"gst"`
+	ExecutedAt     time.Time `db:"executed_at"`
+}
+
+// Broker places buy orders on the exchange on Stocky's behalf.
+// PlaceOrder may be called again for an order whose outcome was never
+// recorded, so implementations must treat Order.ID as an idempotency key
+// and return the original execution rather than buying twice.
+type Broker interface {
+	Name() string
+	PlaceOrder(ctx context.Context, order Order) (Execution, error)
+}
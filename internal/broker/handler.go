@@ -0,0 +1,72 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type OrderHandler struct {
+	service *OrderService
+}
+
+func NewOrderHandler(service *OrderService) *OrderHandler {
+	return &OrderHandler{service: service}
+}
+
+// RunBatch handles POST /admin/broker/batches
+func (h *OrderHandler) RunBatch(c *gin.Context) {
+	result, err := h.service.RunBatch(context.Background())
+	if err != nil {
+		logger.Log.Errorf("Manual broker batch failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to run broker batch"})
+		return
+	}
+
+	logger.Log.WithField("orders", len(result.Orders)).Info("Manual broker batch completed")
+	c.JSON(http.StatusOK, result)
+}
+
+// ListOrders handles GET /admin/broker/orders?limit=50
+func (h *OrderHandler) ListOrders(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	orders, err := h.service.ListOrders(context.Background(), limit)
+	if err != nil {
+		logger.Log.Errorf("Failed to list broker orders: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list orders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"orders": orders})
+}
+
+// GetOrder handles GET /admin/broker/orders/:id
+func (h *OrderHandler) GetOrder(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	detail, err := h.service.GetOrder(context.Background(), orderID)
+	if errors.Is(err, ErrOrderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Failed to fetch broker order %d: %v", orderID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch order"})
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
@@ -0,0 +1,53 @@
+package broker
+
+import "time"
+
+const (
+	OrderSubmitted       = "submitted"
+	OrderFilled          = "filled"
+	OrderPartiallyFilled = "partially_filled"
+	OrderRejected        = "rejected"
+)
+
+type BrokerOrder struct {
+	ID                int        `db:"id" json:"id"`
+	Broker            string     `db:"broker" json:"broker"`
+	BrokerRef         string     `db:"broker_ref" json:"broker_ref"`
+	Symbol            string     `db:"stock_symbol" json:"stock_symbol"`
+	RequestedQuantity float64    `db:"requested_quantity" json:"requested_quantity"`
+	FilledQuantity    float64    `db:"filled_quantity" json:"filled_quantity"`
+	AvgPrice          float64    `db:"avg_price" json:"avg_price"`
+	BrokerageFee      float64    `db:"brokerage_fee" json:"brokerage_fee"`
+	STT               float64    `db:"stt" json:"stt"`
+	GST               float64    `db:"gst" json:"gst"`
+	Status            string     `db:"status" json:"status"`
+	Error             string     `db:"error" json:"error,omitempty"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+	ExecutedAt        *time.Time `db:"executed_at" json:"executed_at"`
+}
+
+type Allocation struct {
+	ID        int       `db:"id" json:"id"`
+	OrderID   int       `db:"order_id" json:"order_id"`
+	RewardID  int       `db:"reward_id" json:"reward_id"`
+	Quantity  float64   `db:"quantity" json:"quantity"`
+	Price     float64   `db:"price" json:"price"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type OrderDetail struct {
+	Order       BrokerOrder  `json:"order"`
+	Allocations []Allocation `json:"allocations"`
+}
+
+// BatchResult summarises one batching run
+type BatchResult struct {
+	Skipped string        `json:"skipped,omitempty"`
+	Orders  []BrokerOrder `json:"orders"`
+}
+
+// candidate is a reward waiting for (the rest of) its shares
+type candidate struct {
+	ID        int     `db:"id"`
+	Remaining float64 `db:"remaining"`
+}
@@ -0,0 +1,393 @@
+package broker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+	"github.com/angad363/stocky-assignment/internal/reward"
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrOrderNotFound = errors.New("broker order not found")
+
+// OrderService batches pending rewards into per-symbol buy orders and
+// allocates the resulting fills back to the individual rewards
+type OrderService struct {
+	db       *sqlx.DB
+	broker   Broker
+	calendar *calendar.Calendar
+}
+
+func NewOrderService(db *sqlx.DB, broker Broker, cal *calendar.Calendar) *OrderService {
+	return &OrderService{db: db, broker: broker, calendar: cal}
+}
+
+const orderColumns = `id, broker, broker_ref, stock_symbol, requested_quantity, filled_quantity,
+	avg_price, brokerage_fee, stt, gst, status, error, created_at, executed_at`
+
+// RunBatch places one order per symbol for every reward whose purchase
+// window has opened. Nothing is sent while the market is closed. Orders
+// left submitted by an earlier run that stopped before recording the
+// outcome are sent again first.
+func (s *OrderService) RunBatch(ctx context.Context) (BatchResult, error) {
+	result := BatchResult{Orders: []BrokerOrder{}}
+
+	now := time.Now()
+	if !s.calendar.IsOpen(now) {
+		result.Skipped = "market closed"
+		return result, nil
+	}
+
+	// Orders this young may still be in flight from a concurrent batch
+	stale := []BrokerOrder{}
+	err := s.db.SelectContext(ctx, &stale, `
+		SELECT `+orderColumns+`
+		FROM broker_orders
+		WHERE status = $1 AND created_at < $2
+		ORDER BY id
+	`, OrderSubmitted, now.Add(-resumeAfter))
+	if err != nil {
+		return result, err
+	}
+	for _, order := range stale {
+		order, err := s.execute(ctx, order)
+		if err != nil {
+			return result, err
+		}
+		result.Orders = append(result.Orders, order)
+	}
+
+	symbols := []string{}
+	err = s.db.SelectContext(ctx, &symbols, `
+		SELECT DISTINCT stock_symbol
+		FROM rewards
+		WHERE status IN ($1, $2)
+		  AND COALESCE(purchase_after, rewarded_at) <= $3
+		ORDER BY stock_symbol
+	`, reward.StatusPending, reward.StatusOrdered, now)
+	if err != nil {
+		return result, err
+	}
+
+	for _, symbol := range symbols {
+		order, err := s.submit(ctx, symbol, now)
+		if err != nil {
+			return result, err
+		}
+		if order.ID == 0 {
+			continue
+		}
+		if order, err = s.execute(ctx, order); err != nil {
+			return result, err
+		}
+		result.Orders = append(result.Orders, order)
+	}
+	return result, nil
+}
+
+// resumeAfter is how long a submitted order may go without a recorded
+// outcome before a batch sends it to the broker again
+const resumeAfter = time.Minute
+
+// submit commits a submitted order for every reward of the symbol still
+// waiting for shares, with the quantity each is owed, and moves pending
+// rewards to ordered. Rewards already on a submitted order are skipped.
+func (s *OrderService) submit(ctx context.Context, symbol string, now time.Time) (BrokerOrder, error) {
+	var order BrokerOrder
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return order, err
+	}
+	defer tx.Rollback()
+
+	// Rewards already ordered are partial fills carrying over their remainder
+	candidates := []candidate{}
+	err = tx.SelectContext(ctx, &candidates, `
+		SELECT r.id, r.quantity - COALESCE(a.allocated, 0) AS remaining
+		FROM rewards r
+		LEFT JOIN (
+			SELECT reward_id, SUM(quantity) AS allocated
+			FROM broker_allocations
+			GROUP BY reward_id
+		) a ON a.reward_id = r.id
+		WHERE r.stock_symbol = $1
+		  AND r.status IN ($2, $3)
+		  AND COALESCE(r.purchase_after, r.rewarded_at) <= $4
+		  AND NOT EXISTS (
+			SELECT 1 FROM broker_order_items i
+			JOIN broker_orders o ON o.id = i.order_id
+			WHERE i.reward_id = r.id AND o.status = $5
+		  )
+		ORDER BY r.rewarded_at, r.id
+		FOR UPDATE OF r SKIP LOCKED
+	`, symbol, reward.StatusPending, reward.StatusOrdered, now, OrderSubmitted)
+	if err != nil {
+		return order, err
+	}
+
+	total := 0.0
+	for _, c := range candidates {
+		total += c.Remaining
+	}
+	total = roundQty(total)
+	if total <= 0 {
+		return order, nil
+	}
+
+	err = tx.GetContext(ctx, &order, `
+		INSERT INTO broker_orders (broker, stock_symbol, requested_quantity, status)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+orderColumns,
+		s.broker.Name(), symbol, total, OrderSubmitted,
+	)
+	if err != nil {
+		return order, err
+	}
+
+	for _, c := range candidates {
+		r, err := reward.LockReward(ctx, tx, c.ID)
+		if err != nil {
+			return order, err
+		}
+		if r.Status == reward.StatusPending {
+			if _, err := reward.Transition(ctx, tx, &r, reward.StatusOrdered, orderNote(order.ID)); err != nil {
+				return order, err
+			}
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO broker_order_items (order_id, reward_id, quantity) VALUES ($1, $2, $3)
+		`, order.ID, c.ID, roundQty(c.Remaining))
+		if err != nil {
+			return order, err
+		}
+	}
+
+	return order, tx.Commit()
+}
+
+// execute sends a submitted order to the broker, outside any transaction,
+// and records the outcome. The broker dedupes on the order ID, so sending
+// an order again returns its original execution.
+func (s *OrderService) execute(ctx context.Context, order BrokerOrder) (BrokerOrder, error) {
+	exec, placeErr := s.broker.PlaceOrder(ctx, Order{ID: order.ID, Symbol: order.Symbol, Quantity: order.RequestedQuantity})
+	return s.record(ctx, order.ID, exec, placeErr)
+}
+
+// record stores a broker's answer for a submitted order: a rejection
+// returns its rewards to pending, a fill is allocated to them. An order
+// that is no longer submitted was recorded already and is returned as is.
+func (s *OrderService) record(ctx context.Context, orderID int, exec Execution, placeErr error) (BrokerOrder, error) {
+	var order BrokerOrder
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return order, err
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &order, `
+		SELECT `+orderColumns+` FROM broker_orders WHERE id = $1 FOR UPDATE
+	`, orderID)
+	if err != nil {
+		return order, err
+	}
+	if order.Status != OrderSubmitted {
+		return order, nil
+	}
+
+	items := []candidate{}
+	err = tx.SelectContext(ctx, &items, `
+		SELECT i.reward_id AS id, i.quantity AS remaining
+		FROM broker_order_items i
+		JOIN rewards r ON r.id = i.reward_id
+		WHERE i.order_id = $1
+		ORDER BY r.rewarded_at, r.id
+	`, orderID)
+	if err != nil {
+		return order, err
+	}
+	locked := make([]reward.Reward, 0, len(items))
+	for _, item := range items {
+		r, err := reward.LockReward(ctx, tx, item.ID)
+		if err != nil {
+			return order, err
+		}
+		locked = append(locked, r)
+	}
+
+	if placeErr != nil {
+		// Requeue rewards with nothing bought yet; partly filled ones stay
+		// ordered and the rejection itself is kept on the order row
+		for i, item := range items {
+			allocated, err := allocatedQty(ctx, tx, item.ID)
+			if err != nil {
+				return order, err
+			}
+			if locked[i].Status != reward.StatusOrdered || allocated > 0 {
+				continue
+			}
+			if _, err := reward.Transition(ctx, tx, &locked[i], reward.StatusPending, "order rejected: "+placeErr.Error()); err != nil {
+				return order, err
+			}
+		}
+		order.Status = OrderRejected
+		order.Error = placeErr.Error()
+		_, err = tx.ExecContext(ctx, `
+			UPDATE broker_orders SET status = $2, error = $3 WHERE id = $1
+		`, order.ID, order.Status, order.Error)
+		if err != nil {
+			return order, err
+		}
+		return order, tx.Commit()
+	}
+
+	if err := s.allocate(ctx, tx, order, exec, items, locked); err != nil {
+		return order, err
+	}
+
+	order.BrokerRef = exec.BrokerRef
+	order.FilledQuantity = exec.FilledQuantity
+	order.AvgPrice = exec.AvgPrice
+	order.BrokerageFee = exec.Brokerage
+	order.STT = exec.STT
+	order.GST = exec.GST
+	order.ExecutedAt = &exec.ExecutedAt
+	order.Status = OrderFilled
+	if exec.FilledQuantity < order.RequestedQuantity {
+		order.Status = OrderPartiallyFilled
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE broker_orders
+		SET broker_ref = $2, filled_quantity = $3, avg_price = $4, brokerage_fee = $5,
+		    stt = $6, gst = $7, status = $8, executed_at = $9
+		WHERE id = $1
+	`, order.ID, order.BrokerRef, order.FilledQuantity, order.AvgPrice, order.BrokerageFee,
+		order.STT, order.GST, order.Status, order.ExecutedAt)
+	if err != nil {
+		return order, err
+	}
+
+	return order, tx.Commit()
+}
+
+// allocate splits a fill across rewards oldest first. Each allocation posts
+// a purchase ledger entry at the execution price with a pro-rata share of
+// the order's fees; a reward becomes purchased once it is fully allocated.
+// Rewards on the order are locked against manual transitions, so one that
+// is no longer ordered means the rows were changed by hand; its share is
+// left unallocated and logged.
+func (s *OrderService) allocate(ctx context.Context, tx *sqlx.Tx, order BrokerOrder, exec Execution,
+	items []candidate, locked []reward.Reward) error {
+	left := exec.FilledQuantity
+
+	for i, c := range items {
+		if locked[i].Status != reward.StatusOrdered {
+			logger.Log.WithFields(map[string]interface{}{
+				"order_id":  order.ID,
+				"reward_id": c.ID,
+				"status":    locked[i].Status,
+			}).Error("Filled order contains a reward that is no longer ordered; leaving its share unallocated")
+			continue
+		}
+		qty := roundQty(math.Min(c.Remaining, left))
+		if qty <= 0 {
+			break
+		}
+		left = roundQty(left - qty)
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO broker_allocations (order_id, reward_id, quantity, price, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`, order.ID, c.ID, qty, exec.AvgPrice, exec.ExecutedAt)
+		if err != nil {
+			return err
+		}
+
+		var eventID *int
+		if qty >= c.Remaining {
+			id, err := reward.Transition(ctx, tx, &locked[i], reward.StatusPurchased, orderNote(order.ID))
+			if err != nil {
+				return err
+			}
+			eventID = &id
+		}
+
+		share := qty / exec.FilledQuantity
+		err = reward.PostLedgerEntry(ctx, tx, reward.LedgerEntry{
+			RewardID:      &locked[i].ID,
+			RewardEventID: eventID,
+			EntryType:     "purchase",
+			StockSymbol:   order.Symbol,
+			StockUnits:    qty,
+			CashOutflow:   reward.RoundINR(qty * exec.AvgPrice),
+			BrokerageFee:  reward.RoundINR(exec.Brokerage * share),
+			STT:           reward.RoundINR(exec.STT * share),
+			GST:           reward.RoundINR(exec.GST * share),
+			CreatedAt:     exec.ExecutedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *OrderService) ListOrders(ctx context.Context, limit int) ([]BrokerOrder, error) {
+	orders := []BrokerOrder{}
+	err := s.db.SelectContext(ctx, &orders, `
+		SELECT `+orderColumns+`
+		FROM broker_orders
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1
+	`, limit)
+	return orders, err
+}
+
+func (s *OrderService) GetOrder(ctx context.Context, orderID int) (OrderDetail, error) {
+	var detail OrderDetail
+
+	err := s.db.GetContext(ctx, &detail.Order, `
+		SELECT `+orderColumns+` FROM broker_orders WHERE id = $1
+	`, orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return detail, ErrOrderNotFound
+	}
+	if err != nil {
+		return detail, err
+	}
+
+	detail.Allocations = []Allocation{}
+	err = s.db.SelectContext(ctx, &detail.Allocations, `
+		SELECT id, order_id, reward_id, quantity, price, created_at
+		FROM broker_allocations
+		WHERE order_id = $1
+		ORDER BY id
+	`, orderID)
+	return detail, err
+}
+
+// allocatedQty is how much of a reward the broker has filled so far
+func allocatedQty(ctx context.Context, tx *sqlx.Tx, rewardID int) (float64, error) {
+	var qty float64
+	err := tx.GetContext(ctx, &qty, `
+		SELECT COALESCE(SUM(quantity), 0) FROM broker_allocations WHERE reward_id = $1
+	`, rewardID)
+	return qty, err
+}
+
+func orderNote(orderID int) string {
+	return fmt.Sprintf("broker order #%d", orderID)
+}
+
+// roundQty keeps quantities at the NUMERIC(18,6) precision
+func roundQty(q float64) float64 {
+	return math.Round(q*1e6) / 1e6
+}
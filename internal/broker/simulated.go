@@ -0,0 +1,81 @@
+package broker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/price"
+	"github.com/angad363/stocky-assignment/internal/reward"
+	"github.com/jmoiron/sqlx"
+)
+
+// SimulatedBroker fills orders locally at the PriceService price. Like a
+// real broker it keeps its own book of fills by order ID, in
+// simulated_broker_fills, so a resent order is not bought twice even
+// across restarts.
+type SimulatedBroker struct {
+	db              *sqlx.DB
+	priceSvc        *price.PriceService
+	slippageBps     float64 // adverse price move applied to every fill
+	partialFillRate float64 // probability [0,1] that an order is only partly filled
+}
+
+func NewSimulatedBroker(db *sqlx.DB, priceSvc *price.PriceService, slippageBps, partialFillRate float64) *SimulatedBroker {
+	return &SimulatedBroker{
+		db:              db,
+		priceSvc:        priceSvc,
+		slippageBps:     slippageBps,
+		partialFillRate: partialFillRate,
+	}
+}
+
+const fillColumns = `broker_ref, filled_quantity, avg_price, brokerage_fee, stt, gst, executed_at`
+
+func (b *SimulatedBroker) Name() string {
+	return "simulated"
+}
+
+func (b *SimulatedBroker) PlaceOrder(ctx context.Context, order Order) (Execution, error) {
+	var exec Execution
+
+	err := b.db.GetContext(ctx, &exec, `
+		SELECT `+fillColumns+` FROM simulated_broker_fills WHERE order_id = $1
+	`, order.ID)
+	if err == nil {
+		return exec, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return exec, err
+	}
+
+	quote, err := b.priceSvc.GetStockPrice(order.Symbol)
+	if err != nil {
+		return exec, err
+	}
+
+	filled := order.Quantity
+	if rand.Float64() < b.partialFillRate {
+		// fill between 10% and 90%, kept to the 6dp quantity precision
+		filled = math.Floor(order.Quantity*(0.1+0.8*rand.Float64())*1e6) / 1e6
+	}
+
+	avgPrice := quote.Price * (1 + b.slippageBps/10000)
+	fees := reward.EstimateFees(filled * avgPrice)
+	now := time.Now()
+
+	// A concurrent resend of the same order gets the fill stored first
+	err = b.db.GetContext(ctx, &exec, `
+		INSERT INTO simulated_broker_fills (order_id, `+fillColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (order_id) DO UPDATE SET order_id = simulated_broker_fills.order_id
+		RETURNING `+fillColumns,
+		order.ID, fmt.Sprintf("SIM-%d-%d", order.ID, now.UnixNano()), filled, reward.RoundINR(avgPrice),
+		fees.Brokerage, fees.STT, fees.GST, now,
+	)
+	return exec, err
+}
@@ -0,0 +1,29 @@
+package broker
+
+import (
+	"context"
+	"time"
+
+	"github.com/angad363/stocky-assignment/pkg/logger"
+)
+
+// StartOrderBatcher runs a batch on every tick of the given interval
+func StartOrderBatcher(service *OrderService, interval time.Duration) {
+	logger.Log.WithField("interval", interval.String()).Info("📦 Broker order batcher started")
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			result, err := service.RunBatch(context.Background())
+			if err != nil {
+				logger.Log.Errorf("Broker batch failed: %v", err)
+				continue
+			}
+			if result.Skipped != "" {
+				logger.Log.WithField("reason", result.Skipped).Debug("Broker batch skipped")
+				continue
+			}
+			logger.Log.WithField("orders", len(result.Orders)).Info("Broker batch completed")
+		}
+	}()
+}
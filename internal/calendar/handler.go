@@ -0,0 +1,79 @@
+package calendar
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type CalendarHandler struct {
+	calendar *Calendar
+}
+
+func NewCalendarHandler(calendar *Calendar) *CalendarHandler {
+	return &CalendarHandler{calendar: calendar}
+}
+
+// GetMarketStatus handles GET /market/status
+func (h *CalendarHandler) GetMarketStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.calendar.Status(time.Now()))
+}
+
+// ListHolidays handles GET /admin/calendar/holidays?year=2025
+func (h *CalendarHandler) ListHolidays(c *gin.Context) {
+	year := 0
+	if y := c.Query("year"); y != "" {
+		var err error
+		year, err = strconv.Atoi(y)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid year"})
+			return
+		}
+	}
+
+	holidays, err := h.calendar.ListHolidays(context.Background(), year)
+	if err != nil {
+		logger.Log.Errorf("Failed to list market holidays: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list holidays"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"holidays": holidays})
+}
+
+// LoadHolidays handles POST /admin/calendar/holidays
+func (h *CalendarHandler) LoadHolidays(c *gin.Context) {
+	var req LoadHolidaysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Log.Warnf("Invalid holiday load request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	loaded, err := h.calendar.LoadHolidays(context.Background(), req.Holidays)
+	if err != nil {
+		logger.Log.Errorf("Failed to load market holidays: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Log.WithField("count", loaded).Info("Market holidays loaded")
+	c.JSON(http.StatusOK, gin.H{"loaded": loaded})
+}
+
+// DeleteHoliday handles DELETE /admin/calendar/holidays/:date
+func (h *CalendarHandler) DeleteHoliday(c *gin.Context) {
+	date := c.Param("date")
+	if err := h.calendar.DeleteHoliday(context.Background(), date); err != nil {
+		logger.Log.Errorf("Failed to delete market holiday %s: %v", date, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Log.WithField("date", date).Info("Market holiday removed")
+	c.Status(http.StatusNoContent)
+}
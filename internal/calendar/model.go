@@ -0,0 +1,26 @@
+package calendar
+
+import "time"
+
+type Holiday struct {
+	Date        time.Time `db:"holiday_date" json:"date"`
+	Description string    `db:"description" json:"description"`
+}
+
+type HolidayInput struct {
+	Date        string `json:"date" binding:"required"` // YYYY-MM-DD
+	Description string `json:"description"`
+}
+
+type LoadHolidaysRequest struct {
+	Holidays []HolidayInput `json:"holidays" binding:"required"`
+}
+
+// MarketStatus describes the trading session relative to a point in time
+type MarketStatus struct {
+	Now         time.Time `json:"now"`
+	Open        bool      `json:"open"`
+	NextOpen    time.Time `json:"next_open"`
+	LastClose   time.Time `json:"last_close"`
+	LastSession string    `json:"last_session"`
+}
@@ -0,0 +1,231 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const dateLayout = "2006-01-02"
+
+// IST is the exchange timezone for NSE and BSE
+var IST = mustLoadIST()
+
+func mustLoadIST() *time.Location {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		return time.FixedZone("IST", 5*3600+1800)
+	}
+	return loc
+}
+
+// Calendar knows the NSE session hours, weekends and exchange holidays
+type Calendar struct {
+	db         *sqlx.DB
+	openAfter  time.Duration // offset of session open from midnight IST
+	closeAfter time.Duration // offset of session close from midnight IST
+
+	mu       sync.RWMutex
+	holidays map[string]string
+}
+
+// NewCalendar builds a calendar for the given "HH:MM" session bounds
+func NewCalendar(db *sqlx.DB, open, close string) (*Calendar, error) {
+	openAfter, err := parseClock(open)
+	if err != nil {
+		return nil, err
+	}
+	closeAfter, err := parseClock(close)
+	if err != nil {
+		return nil, err
+	}
+	if closeAfter <= openAfter {
+		return nil, fmt.Errorf("market close %s must be after open %s", close, open)
+	}
+
+	return &Calendar{
+		db:         db,
+		openAfter:  openAfter,
+		closeAfter: closeAfter,
+		holidays:   map[string]string{},
+	}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid session time %q: %w", s, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Refresh reloads the holiday list from the database
+func (c *Calendar) Refresh(ctx context.Context) error {
+	holidays, err := c.ListHolidays(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	set := make(map[string]string, len(holidays))
+	for _, h := range holidays {
+		set[h.Date.Format(dateLayout)] = h.Description
+	}
+
+	c.mu.Lock()
+	c.holidays = set
+	c.mu.Unlock()
+	return nil
+}
+
+// ListHolidays returns stored holidays, optionally for a single year
+func (c *Calendar) ListHolidays(ctx context.Context, year int) ([]Holiday, error) {
+	holidays := []Holiday{}
+
+	query := `SELECT holiday_date, description FROM market_holidays`
+	args := []interface{}{}
+	if year > 0 {
+		query += ` WHERE EXTRACT(YEAR FROM holiday_date) = $1`
+		args = append(args, year)
+	}
+	query += ` ORDER BY holiday_date`
+
+	if err := c.db.SelectContext(ctx, &holidays, query, args...); err != nil {
+		return nil, err
+	}
+	return holidays, nil
+}
+
+// LoadHolidays upserts a batch of holidays and refreshes the in-memory list
+func (c *Calendar) LoadHolidays(ctx context.Context, inputs []HolidayInput) (int, error) {
+	tx, err := c.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, in := range inputs {
+		date, err := time.ParseInLocation(dateLayout, in.Date, IST)
+		if err != nil {
+			return 0, fmt.Errorf("invalid holiday date %q", in.Date)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO market_holidays (holiday_date, description)
+			VALUES ($1, $2)
+			ON CONFLICT (holiday_date) DO UPDATE SET description = EXCLUDED.description
+		`, date.Format(dateLayout), in.Description)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(inputs), c.Refresh(ctx)
+}
+
+func (c *Calendar) DeleteHoliday(ctx context.Context, date string) error {
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return fmt.Errorf("invalid holiday date %q", date)
+	}
+	if _, err := c.db.ExecContext(ctx, `DELETE FROM market_holidays WHERE holiday_date = $1`, date); err != nil {
+		return err
+	}
+	return c.Refresh(ctx)
+}
+
+// IsTradingDay reports whether the IST calendar day of t has a session
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	t = t.In(IST)
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+
+	c.mu.RLock()
+	_, holiday := c.holidays[t.Format(dateLayout)]
+	c.mu.RUnlock()
+	return !holiday
+}
+
+// IsOpen reports whether the market is in session at t
+func (c *Calendar) IsOpen(t time.Time) bool {
+	if !c.IsTradingDay(t) {
+		return false
+	}
+	open, close := c.sessionBounds(t)
+	return !t.Before(open) && t.Before(close)
+}
+
+// NextOpen returns the start of the next session strictly after t,
+// or t itself if the market is currently open
+func (c *Calendar) NextOpen(t time.Time) time.Time {
+	if c.IsOpen(t) {
+		return t
+	}
+
+	day := t.In(IST)
+	for i := 0; i < 366; i++ {
+		if c.IsTradingDay(day) {
+			open, _ := c.sessionBounds(day)
+			if open.After(t) {
+				return open
+			}
+		}
+		day = startOfDay(day).AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// LastClose returns the close of the most recent completed session at or before t
+func (c *Calendar) LastClose(t time.Time) time.Time {
+	day := t.In(IST)
+	for i := 0; i < 366; i++ {
+		if c.IsTradingDay(day) {
+			_, close := c.sessionBounds(day)
+			if !close.After(t) {
+				return close
+			}
+		}
+		day = startOfDay(day).Add(-time.Nanosecond)
+	}
+	return t
+}
+
+// PriceAsOf is the timestamp a price observed at t is valid for: t itself
+// during a session, otherwise the close of the last session
+func (c *Calendar) PriceAsOf(t time.Time) time.Time {
+	if c.IsOpen(t) {
+		return t
+	}
+	return c.LastClose(t)
+}
+
+// SessionDate returns the trading day whose closing prices value holdings
+// at the end of the given IST calendar date
+func (c *Calendar) SessionDate(date time.Time) time.Time {
+	endOfDay := startOfDay(date.In(IST)).AddDate(0, 0, 1).Add(-time.Nanosecond)
+	return startOfDay(c.LastClose(endOfDay))
+}
+
+func (c *Calendar) Status(t time.Time) MarketStatus {
+	lastClose := c.LastClose(t)
+	return MarketStatus{
+		Now:         t.In(IST),
+		Open:        c.IsOpen(t),
+		NextOpen:    c.NextOpen(t),
+		LastClose:   lastClose,
+		LastSession: lastClose.Format(dateLayout),
+	}
+}
+
+func (c *Calendar) sessionBounds(t time.Time) (time.Time, time.Time) {
+	day := startOfDay(t.In(IST))
+	return day.Add(c.openAfter), day.Add(c.closeAfter)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
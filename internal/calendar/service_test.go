@@ -0,0 +1,81 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+// October 2025: the 17th is a Friday and the 21st a Tuesday holiday
+func testCalendar(t *testing.T) *Calendar {
+	t.Helper()
+	c, err := NewCalendar(nil, "09:15", "15:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.holidays["2025-10-21"] = "Diwali Laxmi Pujan"
+	return c
+}
+
+func ist(day, hour, min int) time.Time {
+	return time.Date(2025, time.October, day, hour, min, 0, 0, IST)
+}
+
+func TestNextOpen(t *testing.T) {
+	c := testCalendar(t)
+	tests := []struct {
+		name string
+		at   time.Time
+		want time.Time
+	}{
+		{"in session", ist(13, 10, 0), ist(13, 10, 0)},
+		{"before open", ist(13, 8, 0), ist(13, 9, 15)},
+		{"at open", ist(13, 9, 15), ist(13, 9, 15)},
+		{"at close", ist(13, 15, 30), ist(14, 9, 15)},
+		{"friday evening", ist(17, 16, 0), ist(20, 9, 15)},
+		{"saturday", ist(18, 12, 0), ist(20, 9, 15)},
+		{"before a holiday", ist(20, 16, 0), ist(22, 9, 15)},
+		{"on a holiday", ist(21, 10, 0), ist(22, 9, 15)},
+		{"UTC input", time.Date(2025, time.October, 13, 2, 0, 0, 0, time.UTC), ist(13, 9, 15)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.NextOpen(tt.at); !got.Equal(tt.want) {
+				t.Errorf("NextOpen(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastClose(t *testing.T) {
+	c := testCalendar(t)
+	tests := []struct {
+		name string
+		at   time.Time
+		want time.Time
+	}{
+		{"in session", ist(14, 10, 0), ist(13, 15, 30)},
+		{"at close", ist(14, 15, 30), ist(14, 15, 30)},
+		{"after close", ist(14, 20, 0), ist(14, 15, 30)},
+		{"monday morning", ist(20, 9, 0), ist(17, 15, 30)},
+		{"sunday", ist(19, 12, 0), ist(17, 15, 30)},
+		{"on a holiday", ist(21, 16, 0), ist(20, 15, 30)},
+		{"after a holiday", ist(22, 9, 0), ist(20, 15, 30)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.LastClose(tt.at); !got.Equal(tt.want) {
+				t.Errorf("LastClose(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCalendarRejectsCloseBeforeOpen(t *testing.T) {
+	for _, bounds := range [][2]string{{"15:30", "09:15"}, {"09:15", "09:15"}, {"9am", "15:30"}} {
+		if _, err := NewCalendar(nil, bounds[0], bounds[1]); err == nil {
+			t.Errorf("NewCalendar(%q, %q) succeeded", bounds[0], bounds[1])
+		}
+	}
+}
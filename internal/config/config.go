@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +16,58 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	ServerPort string
+
+	// NSE session hours in IST, "HH:MM"
+	MarketOpen  string
+	MarketClose string
+
+	// Manual rewards worth more than this (INR) need a second admin's approval; 0 disables
+	RewardApprovalThreshold float64
+
+	// Rewards for users without verified KYC are clawed back after KYCHoldDays
+	KYCHoldDays       int
+	KYCExpiryInterval time.Duration
+
+	// Broker order batching and the simulated broker
+	BrokerBatchInterval   time.Duration
+	BrokerSlippageBps     float64
+	BrokerPartialFillRate float64
+
+	// Outbox relay: comma-separated sinks ("log", "redis", "webhook", "notifications")
+	OutboxSinks        string
+	OutboxStream       string
+	OutboxPollInterval time.Duration
+	OutboxMaxAttempts  int
+
+	// Outbound webhook delivery
+	WebhookMaxAttempts  int
+	WebhookBackoffBase  time.Duration
+	WebhookTimeout      time.Duration
+	WebhookPollInterval time.Duration
+
+	// JWT signing keys as "kid:secret,kid:secret"; JWTActiveKey signs new tokens
+	JWTKeys         string
+	JWTActiveKey    string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// Comma-separated user IDs granted the admin role at startup
+	AdminUserIDs string
+
+	// Per-route sliding-window limits, see server.ParseRateLimits
+	RateLimits string
+
+	// Comma-separated proxy IPs or CIDRs whose X-Forwarded-For is trusted
+	// for the client IP; empty trusts none and uses the peer address
+	TrustedProxies string
+
+	// Ledger names for exported accounts as "code=Name;code=Name", matched
+	// by account code prefix; see accounting.ParseAccountMap
+	AccountingExportMap string
+	TallyCompany        string
+
+	// IST time of day ("HH:MM") the nightly reconciliation runs
+	ReconciliationAt string
 }
 
 func Load() *Config {
@@ -29,5 +83,98 @@ func Load() *Config {
 		DBPassword: os.Getenv("DB_PASSWORD"),
 		DBName:     os.Getenv("DB_NAME"),
 		ServerPort: os.Getenv("SERVER_PORT"),
+
+		MarketOpen:  getEnv("MARKET_OPEN", "09:15"),
+		MarketClose: getEnv("MARKET_CLOSE", "15:30"),
+
+		RewardApprovalThreshold: getEnvFloat("REWARD_APPROVAL_THRESHOLD_INR", 50000),
+
+		KYCHoldDays:       getEnvInt("KYC_HOLD_DAYS", 30),
+		KYCExpiryInterval: getEnvDuration("KYC_EXPIRY_INTERVAL", time.Hour),
+
+		BrokerBatchInterval:   getEnvDuration("BROKER_BATCH_INTERVAL", 15*time.Minute),
+		BrokerSlippageBps:     getEnvFloat("BROKER_SLIPPAGE_BPS", 5),
+		BrokerPartialFillRate: getEnvFloat("BROKER_PARTIAL_FILL_RATE", 0),
+
+		OutboxSinks:        getEnv("OUTBOX_SINKS", "log,redis,webhook,notifications"),
+		OutboxStream:       getEnv("OUTBOX_STREAM", "stocky:events"),
+		OutboxPollInterval: getEnvDuration("OUTBOX_POLL_INTERVAL", 2*time.Second),
+		OutboxMaxAttempts:  getEnvInt("OUTBOX_MAX_ATTEMPTS", 10),
+
+		WebhookMaxAttempts:  getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookBackoffBase:  getEnvDuration("WEBHOOK_BACKOFF_BASE", 30*time.Second),
+		WebhookTimeout:      getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookPollInterval: getEnvDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+
+		JWTKeys:         os.Getenv("JWT_KEYS"),
+		JWTActiveKey:    os.Getenv("JWT_ACTIVE_KEY"),
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		AdminUserIDs:    os.Getenv("ADMIN_USER_IDS"),
+
+		RateLimits: getEnv("RATE_LIMITS", "*=ip:300/1m,user:300/1m,key:1200/1m;"+
+			"POST /register=ip:5/1m;"+
+			"POST /refer=ip:20/1m,user:10/1h;"+
+			"POST /reward=ip:60/1m,user:30/1m,key:600/1m"),
+		TrustedProxies: os.Getenv("TRUSTED_PROXIES"),
+
+		AccountingExportMap: getEnv("ACCOUNTING_EXPORT_MAP", "assets:cash=Bank Account;"+
+			"assets:stock_inventory=Shares Held for Customers;"+
+			"liabilities:user_holdings=Customer Share Holdings;"+
+			"liabilities:tax_payable=Duties and Taxes Payable;"+
+			"expenses:rewards=Customer Reward Expense;"+
+			"expenses:fees=Brokerage and Charges;"+
+			"expenses:taxes=STT and GST;"+
+			"equity:rounding=Rounding Off"),
+		TallyCompany: os.Getenv("TALLY_COMPANY"),
+
+		ReconciliationAt: getEnv("RECONCILIATION_AT", "02:00"),
+	}
+}
+
+// getEnv returns the environment value for key, or def when unset
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func getEnvFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %v", key, v, def)
+		return def
+	}
+	return f
+}
+
+func getEnvInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i <= 0 {
+		log.Printf("Warning: invalid %s=%q, using %v", key, v, def)
+		return def
+	}
+	return i
+}
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("Warning: invalid %s=%q, using %v", key, v, def)
+		return def
 	}
+	return d
 }
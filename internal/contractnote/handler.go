@@ -0,0 +1,137 @@
+package contractnote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type ContractNoteHandler struct {
+	service *ContractNoteService
+}
+
+func NewContractNoteHandler(service *ContractNoteService) *ContractNoteHandler {
+	return &ContractNoteHandler{service: service}
+}
+
+// UploadContractNote handles POST /admin/contract-notes (multipart field "file")
+func (h *ContractNoteHandler) UploadContractNote(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	trades, err := ParseCSV(file)
+	if err != nil {
+		logger.Log.Warnf("Invalid contract note %s: %v", fileHeader.Filename, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.service.Import(context.Background(), fileHeader.Filename, trades)
+	if errors.Is(err, ErrAlreadyImported) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Failed to import contract note %s: %v", fileHeader.Filename, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import contract note"})
+		return
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"note_id": report.Note.ID,
+		"trades":  len(trades),
+		"pending": report.Totals.Pending,
+	}).Info("Contract note imported")
+	c.JSON(http.StatusCreated, report)
+}
+
+func (h *ContractNoteHandler) ListContractNotes(c *gin.Context) {
+	notes, err := h.service.ListNotes(context.Background())
+	if err != nil {
+		logger.Log.Errorf("Failed to list contract notes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list contract notes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"contract_notes": notes})
+}
+
+// GetReconciliation handles GET /admin/contract-notes/:id/reconciliation
+func (h *ContractNoteHandler) GetReconciliation(c *gin.Context) {
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid contract note id"})
+		return
+	}
+
+	report, err := h.service.GetReport(context.Background(), noteID)
+	if errors.Is(err, ErrNoteNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Failed to build reconciliation for note %d: %v", noteID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reconciliation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ApproveDifference handles POST /admin/fee-differences/:id/approve
+func (h *ContractNoteHandler) ApproveDifference(c *gin.Context) {
+	h.decide(c, h.service.Approve)
+}
+
+// RejectDifference handles POST /admin/fee-differences/:id/reject
+func (h *ContractNoteHandler) RejectDifference(c *gin.Context) {
+	h.decide(c, h.service.Reject)
+}
+
+func (h *ContractNoteHandler) decide(c *gin.Context, decide func(context.Context, int, string) (FeeDifference, error)) {
+	diffID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid difference id"})
+		return
+	}
+
+	var req DecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	diff, err := decide(c.Request.Context(), diffID, req.Comment)
+	switch {
+	case errors.Is(err, ErrDifferenceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrAlreadyDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Log.Errorf("Failed to decide fee difference %d: %v", diffID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update fee difference"})
+		return
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"difference_id": diffID,
+		"status":        diff.Status,
+	}).Info("Fee difference decided")
+	c.JSON(http.StatusOK, diff)
+}
@@ -0,0 +1,68 @@
+package contractnote
+
+import "time"
+
+const (
+	DiffMatched  = "matched"
+	DiffPending  = "pending"
+	DiffApproved = "approved"
+	DiffRejected = "rejected"
+)
+
+type ContractNote struct {
+	ID         int       `db:"id" json:"id"`
+	Filename   string    `db:"filename" json:"filename"`
+	TradeCount int       `db:"trade_count" json:"trade_count"`
+	UploadedAt time.Time `db:"uploaded_at" json:"uploaded_at"`
+}
+
+// Trade is one executed line from a broker contract note
+type Trade struct {
+	ID        int       `db:"id" json:"id"`
+	NoteID    int       `db:"note_id" json:"note_id"`
+	TradeDate time.Time `db:"trade_date" json:"trade_date"`
+	Symbol    string    `db:"stock_symbol" json:"stock_symbol"`
+	Quantity  float64   `db:"quantity" json:"quantity"`
+	Price     float64   `db:"price" json:"price"`
+	Brokerage float64   `db:"brokerage_fee" json:"brokerage_fee"`
+	STT       float64   `db:"stt" json:"stt"`
+	GST       float64   `db:"gst" json:"gst"`
+}
+
+// FeeDifference compares estimated ledger fees with contract-note actuals
+// for one trade date and symbol
+type FeeDifference struct {
+	ID                 int        `db:"id" json:"id"`
+	NoteID             int        `db:"note_id" json:"note_id"`
+	TradeDate          time.Time  `db:"trade_date" json:"trade_date"`
+	Symbol             string     `db:"stock_symbol" json:"stock_symbol"`
+	LedgerUnits        float64    `db:"ledger_units" json:"ledger_units"`
+	NoteUnits          float64    `db:"note_units" json:"note_units"`
+	EstimatedBrokerage float64    `db:"estimated_brokerage" json:"estimated_brokerage"`
+	ActualBrokerage    float64    `db:"actual_brokerage" json:"actual_brokerage"`
+	EstimatedSTT       float64    `db:"estimated_stt" json:"estimated_stt"`
+	ActualSTT          float64    `db:"actual_stt" json:"actual_stt"`
+	EstimatedGST       float64    `db:"estimated_gst" json:"estimated_gst"`
+	ActualGST          float64    `db:"actual_gst" json:"actual_gst"`
+	Status             string     `db:"status" json:"status"`
+	Comment            string     `db:"comment" json:"comment"`
+	DecidedAt          *time.Time `db:"decided_at" json:"decided_at"`
+}
+
+// Report is the reconciliation result for a contract note
+type Report struct {
+	Note        ContractNote    `json:"note"`
+	Differences []FeeDifference `json:"differences"`
+	Totals      ReportTotals    `json:"totals"`
+}
+
+type ReportTotals struct {
+	BrokerageDiff float64 `json:"brokerage_diff"`
+	STTDiff       float64 `json:"stt_diff"`
+	GSTDiff       float64 `json:"gst_diff"`
+	Pending       int     `json:"pending"`
+}
+
+type DecisionRequest struct {
+	Comment string `json:"comment"`
+}
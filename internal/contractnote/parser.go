@@ -0,0 +1,103 @@
+package contractnote
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+)
+
+// requiredColumns must appear in the CSV header (case-insensitive)
+var requiredColumns = []string{"trade_date", "symbol", "quantity", "price", "brokerage", "stt", "gst"}
+
+var dateLayouts = []string{"2006-01-02", "02-01-2006", "02/01/2006"}
+
+// ParseCSV reads contract-note trades. Columns are located by header name,
+// so brokers may add extra columns in any order.
+func ParseCSV(r io.Reader) ([]Trade, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	index := map[string]int{}
+	for i, col := range header {
+		index[strings.ToLower(strings.TrimSpace(col))] = i
+	}
+	for _, col := range requiredColumns {
+		if _, ok := index[col]; !ok {
+			return nil, fmt.Errorf("missing column %q", col)
+		}
+	}
+
+	trades := []Trade{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		trade, err := parseRecord(record, index)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		trades = append(trades, trade)
+	}
+
+	if len(trades) == 0 {
+		return nil, fmt.Errorf("contract note has no trades")
+	}
+	return trades, nil
+}
+
+func parseRecord(record []string, index map[string]int) (Trade, error) {
+	var t Trade
+	var err error
+
+	field := func(name string) string {
+		return strings.TrimSpace(record[index[name]])
+	}
+
+	if t.TradeDate, err = parseDate(field("trade_date")); err != nil {
+		return t, err
+	}
+	t.Symbol = strings.ToUpper(field("symbol"))
+	if t.Symbol == "" {
+		return t, fmt.Errorf("symbol is empty")
+	}
+
+	numbers := map[string]*float64{
+		"quantity":  &t.Quantity,
+		"price":     &t.Price,
+		"brokerage": &t.Brokerage,
+		"stt":       &t.STT,
+		"gst":       &t.GST,
+	}
+	for name, dst := range numbers {
+		v, err := strconv.ParseFloat(strings.ReplaceAll(field(name), ",", ""), 64)
+		if err != nil {
+			return t, fmt.Errorf("invalid %s %q", name, field(name))
+		}
+		*dst = v
+	}
+	return t, nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, calendar.IST); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid trade_date %q", s)
+}
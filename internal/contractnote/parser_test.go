@@ -0,0 +1,62 @@
+package contractnote
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+)
+
+// A broker export with its own column order, an extra column, quoted
+// thousands separators and two different date layouts
+const brokerNote = `Order ID, GST, STT, Brokerage, Price, Quantity, Symbol, Trade_Date
+A1, 0.54, 10.06, 3.02, "2,875.40", 3.5, reliance, 10-11-2025
+A2, 0, 0, 0, 100, 1, infy, 11/11/2025
+A3, 0.1, 1, 1, 3900, 2, TCS, 2025-11-12
+`
+
+func TestParseCSV(t *testing.T) {
+	trades, err := ParseCSV(strings.NewReader(brokerNote))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(d int) time.Time { return time.Date(2025, time.November, d, 0, 0, 0, 0, calendar.IST) }
+	want := []Trade{
+		{TradeDate: day(10), Symbol: "RELIANCE", Quantity: 3.5, Price: 2875.40, Brokerage: 3.02, STT: 10.06, GST: 0.54},
+		{TradeDate: day(11), Symbol: "INFY", Quantity: 1, Price: 100},
+		{TradeDate: day(12), Symbol: "TCS", Quantity: 2, Price: 3900, Brokerage: 1, STT: 1, GST: 0.1},
+	}
+	if len(trades) != len(want) {
+		t.Fatalf("got %d trades, want %d", len(trades), len(want))
+	}
+	for i := range want {
+		got := trades[i]
+		if !got.TradeDate.Equal(want[i].TradeDate) {
+			t.Errorf("trade %d date = %v, want %v", i, got.TradeDate, want[i].TradeDate)
+		}
+		got.TradeDate = want[i].TradeDate
+		if got != want[i] {
+			t.Errorf("trade %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	const header = "trade_date,symbol,quantity,price,brokerage,stt,gst\n"
+	cases := []struct{ csv, err string }{
+		{"", "reading header"},
+		{"trade_date,symbol,quantity,price,brokerage,stt\n", `missing column "gst"`},
+		{header, "no trades"},
+		{header + "2025-11-10,TCS,one,100,1,1,1\n", "line 2: invalid quantity"},
+		{header + "2025/11/10,TCS,1,100,1,1,1\n", "line 2: invalid trade_date"},
+		{header + "2025-11-10, ,1,100,1,1,1\n", "line 2: symbol is empty"},
+	}
+	for _, c := range cases {
+		_, err := ParseCSV(strings.NewReader(c.csv))
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("ParseCSV(%q) err = %v, want %q", c.csv, err, c.err)
+		}
+	}
+}
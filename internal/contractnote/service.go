@@ -0,0 +1,239 @@
+package contractnote
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/angad363/stocky-assignment/internal/reward"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// feeTolerance is the INR difference below which a line counts as matched
+const feeTolerance = 0.01
+
+var (
+	ErrNoteNotFound       = errors.New("contract note not found")
+	ErrDifferenceNotFound = errors.New("fee difference not found")
+	ErrAlreadyDecided     = errors.New("fee difference is not pending")
+	ErrAlreadyImported    = errors.New("a pending or approved fee difference already exists for a trade date and symbol in this note")
+)
+
+// ContractNoteService imports broker contract notes and reconciles their
+// actual charges against the fees estimated in the ledger
+type ContractNoteService struct {
+	db *sqlx.DB
+}
+
+func NewContractNoteService(db *sqlx.DB) *ContractNoteService {
+	return &ContractNoteService{db: db}
+}
+
+const differenceColumns = `id, note_id, trade_date, stock_symbol, ledger_units, note_units,
+	estimated_brokerage, actual_brokerage, estimated_stt, actual_stt,
+	estimated_gst, actual_gst, status, comment, decided_at`
+
+// Import stores the trades of a contract note and computes the fee
+// differences per trade date and symbol in one transaction
+func (s *ContractNoteService) Import(ctx context.Context, filename string, trades []Trade) (Report, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return Report{}, err
+	}
+	defer tx.Rollback()
+
+	var noteID int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO contract_notes (filename, trade_count) VALUES ($1, $2) RETURNING id
+	`, filename, len(trades)).Scan(&noteID)
+	if err != nil {
+		return Report{}, err
+	}
+
+	for _, t := range trades {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO contract_note_trades
+				(note_id, trade_date, stock_symbol, quantity, price, brokerage_fee, stt, gst)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, noteID, t.TradeDate.Format("2006-01-02"), t.Symbol, t.Quantity, t.Price, t.Brokerage, t.STT, t.GST)
+		if err != nil {
+			return Report{}, err
+		}
+	}
+
+	// Estimates include earlier fee adjustments, so re-importing a note whose
+	// differences were already approved comes out matched. Any other open
+	// difference for the same trade date and symbol violates
+	// fee_differences_open_idx and the whole note is refused.
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO fee_differences
+			(note_id, trade_date, stock_symbol, ledger_units, note_units,
+			 estimated_brokerage, actual_brokerage, estimated_stt, actual_stt,
+			 estimated_gst, actual_gst, status)
+		SELECT t.note_id, t.trade_date, t.stock_symbol,
+		       COALESCE(l.units, 0), t.units,
+		       COALESCE(l.brokerage, 0), t.brokerage,
+		       COALESCE(l.stt, 0), t.stt,
+		       COALESCE(l.gst, 0), t.gst,
+		       CASE WHEN ABS(t.brokerage - COALESCE(l.brokerage, 0)) < $2
+		             AND ABS(t.stt - COALESCE(l.stt, 0)) < $2
+		             AND ABS(t.gst - COALESCE(l.gst, 0)) < $2
+		            THEN $3 ELSE $4 END
+		FROM (
+			SELECT note_id, trade_date, stock_symbol, SUM(quantity) AS units,
+			       SUM(brokerage_fee) AS brokerage, SUM(stt) AS stt, SUM(gst) AS gst
+			FROM contract_note_trades
+			WHERE note_id = $1
+			GROUP BY note_id, trade_date, stock_symbol
+		) t
+		LEFT JOIN (
+			SELECT (created_at AT TIME ZONE 'Asia/Kolkata')::date AS trade_date, stock_symbol,
+			       SUM(stock_units) AS units, SUM(brokerage_fee) AS brokerage,
+			       SUM(stt) AS stt, SUM(gst) AS gst
+			FROM ledger_entries
+			WHERE entry_type IN ('purchase', 'fee_adjustment')
+			GROUP BY 1, 2
+		) l ON l.trade_date = t.trade_date AND l.stock_symbol = t.stock_symbol
+	`, noteID, feeTolerance, DiffMatched, DiffPending)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return Report{}, ErrAlreadyImported
+	}
+	if err != nil {
+		return Report{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Report{}, err
+	}
+	return s.GetReport(ctx, noteID)
+}
+
+func (s *ContractNoteService) ListNotes(ctx context.Context) ([]ContractNote, error) {
+	notes := []ContractNote{}
+	err := s.db.SelectContext(ctx, &notes, `
+		SELECT id, filename, trade_count, uploaded_at
+		FROM contract_notes
+		ORDER BY uploaded_at DESC, id DESC
+	`)
+	return notes, err
+}
+
+// GetReport returns the reconciliation of a contract note
+func (s *ContractNoteService) GetReport(ctx context.Context, noteID int) (Report, error) {
+	var report Report
+
+	err := s.db.GetContext(ctx, &report.Note, `
+		SELECT id, filename, trade_count, uploaded_at FROM contract_notes WHERE id = $1
+	`, noteID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return report, ErrNoteNotFound
+	}
+	if err != nil {
+		return report, err
+	}
+
+	report.Differences = []FeeDifference{}
+	err = s.db.SelectContext(ctx, &report.Differences, `
+		SELECT `+differenceColumns+`
+		FROM fee_differences
+		WHERE note_id = $1
+		ORDER BY trade_date, stock_symbol
+	`, noteID)
+	if err != nil {
+		return report, err
+	}
+
+	for _, d := range report.Differences {
+		report.Totals.BrokerageDiff += d.ActualBrokerage - d.EstimatedBrokerage
+		report.Totals.STTDiff += d.ActualSTT - d.EstimatedSTT
+		report.Totals.GSTDiff += d.ActualGST - d.EstimatedGST
+		if d.Status == DiffPending {
+			report.Totals.Pending++
+		}
+	}
+	report.Totals.BrokerageDiff = reward.RoundINR(report.Totals.BrokerageDiff)
+	report.Totals.STTDiff = reward.RoundINR(report.Totals.STTDiff)
+	report.Totals.GSTDiff = reward.RoundINR(report.Totals.GSTDiff)
+	return report, nil
+}
+
+// Approve accepts a difference and posts an adjusting ledger entry dated on
+// the trade date, so the ledger carries the broker's actual charges
+func (s *ContractNoteService) Approve(ctx context.Context, diffID int, comment string) (FeeDifference, error) {
+	return s.decide(ctx, diffID, DiffApproved, comment)
+}
+
+func (s *ContractNoteService) Reject(ctx context.Context, diffID int, comment string) (FeeDifference, error) {
+	return s.decide(ctx, diffID, DiffRejected, comment)
+}
+
+func (s *ContractNoteService) decide(ctx context.Context, diffID int, status, comment string) (FeeDifference, error) {
+	var diff FeeDifference
+
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return diff, err
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &diff, `
+		SELECT `+differenceColumns+` FROM fee_differences WHERE id = $1 FOR UPDATE
+	`, diffID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return diff, ErrDifferenceNotFound
+	}
+	if err != nil {
+		return diff, err
+	}
+	if diff.Status != DiffPending {
+		return diff, ErrAlreadyDecided
+	}
+	before := diff
+
+	if status == DiffApproved {
+		err = reward.PostLedgerEntry(ctx, tx.Tx, reward.LedgerEntry{
+			EntryType:    "fee_adjustment",
+			StockSymbol:  diff.Symbol,
+			BrokerageFee: reward.RoundINR(diff.ActualBrokerage - diff.EstimatedBrokerage),
+			STT:          reward.RoundINR(diff.ActualSTT - diff.EstimatedSTT),
+			GST:          reward.RoundINR(diff.ActualGST - diff.EstimatedGST),
+			CreatedAt:    diff.TradeDate,
+		})
+		if err != nil {
+			return diff, err
+		}
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+		UPDATE fee_differences SET status = $2, comment = $3, decided_at = $4 WHERE id = $1
+	`, diffID, status, comment, now)
+	if err != nil {
+		return diff, err
+	}
+
+	diff.Status = status
+	diff.Comment = comment
+	diff.DecidedAt = &now
+
+	action := audit.ActionFeeDiffApproved
+	if status == DiffRejected {
+		action = audit.ActionFeeDiffRejected
+	}
+	err = audit.Record(ctx, tx, audit.Entry{
+		Action:     action,
+		EntityType: "fee_difference",
+		EntityID:   strconv.Itoa(diff.ID),
+		Before:     before,
+		After:      diff,
+	})
+	if err != nil {
+		return diff, err
+	}
+	return diff, tx.Commit()
+}
@@ -0,0 +1,10 @@
+package db
+
+// Advisory lock keys. Each must be unique: two features sharing a key
+// would block, or be skipped behind, each other's work. 727002 was the
+// audit chain lock, now a row lock on audit_chain_head; it is not reused
+// so a rolling deploy cannot mix the two meanings.
+const (
+	LockOutboxRelay    = 727001 // keeps a single outbox relay active
+	LockReconciliation = 727003 // keeps a single reconciliation run active
+)
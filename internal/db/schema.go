@@ -0,0 +1,560 @@
+package db
+
+import (
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// schema holds the idempotent DDL statements applied at startup, in order
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS users (
+		id         SERIAL PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS rewards (
+		id           SERIAL PRIMARY KEY,
+		user_id      INTEGER NOT NULL REFERENCES users(id),
+		stock_symbol VARCHAR(20) NOT NULL,
+		quantity     NUMERIC(18,6) NOT NULL,
+		rewarded_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS ledger_entries (
+		id            SERIAL PRIMARY KEY,
+		reward_id     INTEGER REFERENCES rewards(id),
+		stock_symbol  VARCHAR(20) NOT NULL,
+		stock_units   NUMERIC(18,6) NOT NULL,
+		cash_outflow  NUMERIC(18,4) NOT NULL DEFAULT 0,
+		brokerage_fee NUMERIC(18,4) NOT NULL DEFAULT 0,
+		stt           NUMERIC(18,4) NOT NULL DEFAULT 0,
+		gst           NUMERIC(18,4) NOT NULL DEFAULT 0,
+		created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS referrals (
+		id          SERIAL PRIMARY KEY,
+		referrer_id INTEGER NOT NULL REFERENCES users(id),
+		friend_name VARCHAR(255) NOT NULL,
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+
+	// Instrument master: every rewardable symbol must be listed here
+	`CREATE TABLE IF NOT EXISTS instruments (
+		symbol        VARCHAR(20) PRIMARY KEY,
+		isin          VARCHAR(12) NOT NULL UNIQUE,
+		exchange      VARCHAR(10) NOT NULL,
+		name          VARCHAR(255) NOT NULL,
+		sector        VARCHAR(100) NOT NULL DEFAULT '',
+		lot_precision SMALLINT NOT NULL DEFAULT 6 CHECK (lot_precision BETWEEN 0 AND 6),
+		status        VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'inactive')),
+		created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`INSERT INTO instruments (symbol, isin, exchange, name, sector) VALUES
+		('RELIANCE',  'INE002A01018', 'NSE', 'Reliance Industries Ltd',   'Energy'),
+		('TCS',       'INE467B01029', 'NSE', 'Tata Consultancy Services', 'Information Technology'),
+		('INFY',      'INE009A01021', 'NSE', 'Infosys Ltd',               'Information Technology'),
+		('HDFC',      'INE001A01036', 'NSE', 'HDFC Ltd',                  'Financial Services'),
+		('ICICIBANK', 'INE090A01021', 'NSE', 'ICICI Bank Ltd',            'Financial Services')
+	ON CONFLICT (symbol) DO NOTHING`,
+
+	// Exchange holidays; weekends are handled in code
+	`CREATE TABLE IF NOT EXISTS market_holidays (
+		holiday_date DATE PRIMARY KEY,
+		description  VARCHAR(255) NOT NULL DEFAULT ''
+	)`,
+	`ALTER TABLE rewards ADD COLUMN IF NOT EXISTS purchase_after TIMESTAMPTZ`,
+	`ALTER TABLE rewards ADD COLUMN IF NOT EXISTS off_market_hours BOOLEAN NOT NULL DEFAULT FALSE`,
+
+	// Reward lifecycle. Rewards written before statuses existed were final,
+	// so they are backfilled as settled before new rows default to pending.
+	`ALTER TABLE rewards ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'settled'
+		CHECK (status IN ('pending', 'ordered', 'purchased', 'settled', 'failed', 'reversed'))`,
+	`ALTER TABLE rewards ALTER COLUMN status SET DEFAULT 'pending'`,
+	`ALTER TABLE rewards ADD COLUMN IF NOT EXISTS status_updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()`,
+	`CREATE TABLE IF NOT EXISTS reward_events (
+		id          SERIAL PRIMARY KEY,
+		reward_id   INTEGER NOT NULL REFERENCES rewards(id),
+		from_status VARCHAR(10) NOT NULL DEFAULT '',
+		to_status   VARCHAR(10) NOT NULL,
+		note        TEXT NOT NULL DEFAULT '',
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS reward_events_reward_id_idx ON reward_events (reward_id)`,
+	`ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS reward_event_id INTEGER REFERENCES reward_events(id)`,
+	`ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS entry_type VARCHAR(20) NOT NULL DEFAULT 'purchase'`,
+
+	// Aggregated broker orders and how their fills were split across rewards
+	`CREATE TABLE IF NOT EXISTS broker_orders (
+		id                 SERIAL PRIMARY KEY,
+		broker             VARCHAR(50) NOT NULL,
+		broker_ref         VARCHAR(100) NOT NULL DEFAULT '',
+		stock_symbol       VARCHAR(20) NOT NULL,
+		requested_quantity NUMERIC(18,6) NOT NULL,
+		filled_quantity    NUMERIC(18,6) NOT NULL DEFAULT 0,
+		avg_price          NUMERIC(18,4) NOT NULL DEFAULT 0,
+		brokerage_fee      NUMERIC(18,4) NOT NULL DEFAULT 0,
+		stt                NUMERIC(18,4) NOT NULL DEFAULT 0,
+		gst                NUMERIC(18,4) NOT NULL DEFAULT 0,
+		status             VARCHAR(20) NOT NULL,
+		error              TEXT NOT NULL DEFAULT '',
+		created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		executed_at        TIMESTAMPTZ
+	)`,
+	`CREATE TABLE IF NOT EXISTS broker_allocations (
+		id         SERIAL PRIMARY KEY,
+		order_id   INTEGER NOT NULL REFERENCES broker_orders(id),
+		reward_id  INTEGER NOT NULL REFERENCES rewards(id),
+		quantity   NUMERIC(18,6) NOT NULL,
+		price      NUMERIC(18,4) NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS broker_allocations_reward_id_idx ON broker_allocations (reward_id)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS broker_allocations_order_reward_idx ON broker_allocations (order_id, reward_id)`,
+	// The rewards an order was placed for and the quantity each was owed,
+	// written when the order is submitted so its fill can be allocated later
+	`CREATE TABLE IF NOT EXISTS broker_order_items (
+		order_id  INTEGER NOT NULL REFERENCES broker_orders(id),
+		reward_id INTEGER NOT NULL REFERENCES rewards(id),
+		quantity  NUMERIC(18,6) NOT NULL,
+		PRIMARY KEY (order_id, reward_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS broker_order_items_reward_id_idx ON broker_order_items (reward_id)`,
+	// The simulated broker's own record of what it filled, so an order
+	// resent after a restart gets its original execution back
+	`CREATE TABLE IF NOT EXISTS simulated_broker_fills (
+		order_id        INTEGER PRIMARY KEY REFERENCES broker_orders(id),
+		broker_ref      VARCHAR(100) NOT NULL,
+		filled_quantity NUMERIC(18,6) NOT NULL,
+		avg_price       NUMERIC(18,4) NOT NULL,
+		brokerage_fee   NUMERIC(18,4) NOT NULL,
+		stt             NUMERIC(18,4) NOT NULL,
+		gst             NUMERIC(18,4) NOT NULL,
+		executed_at     TIMESTAMPTZ NOT NULL
+	)`,
+
+	// Broker contract notes and their reconciliation against ledger fees
+	`CREATE TABLE IF NOT EXISTS contract_notes (
+		id          SERIAL PRIMARY KEY,
+		filename    VARCHAR(255) NOT NULL,
+		trade_count INTEGER NOT NULL,
+		uploaded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS contract_note_trades (
+		id            SERIAL PRIMARY KEY,
+		note_id       INTEGER NOT NULL REFERENCES contract_notes(id),
+		trade_date    DATE NOT NULL,
+		stock_symbol  VARCHAR(20) NOT NULL,
+		quantity      NUMERIC(18,6) NOT NULL,
+		price         NUMERIC(18,4) NOT NULL,
+		brokerage_fee NUMERIC(18,4) NOT NULL,
+		stt           NUMERIC(18,4) NOT NULL,
+		gst           NUMERIC(18,4) NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS fee_differences (
+		id                  SERIAL PRIMARY KEY,
+		note_id             INTEGER NOT NULL REFERENCES contract_notes(id),
+		trade_date          DATE NOT NULL,
+		stock_symbol        VARCHAR(20) NOT NULL,
+		ledger_units        NUMERIC(18,6) NOT NULL,
+		note_units          NUMERIC(18,6) NOT NULL,
+		estimated_brokerage NUMERIC(18,4) NOT NULL,
+		actual_brokerage    NUMERIC(18,4) NOT NULL,
+		estimated_stt       NUMERIC(18,4) NOT NULL,
+		actual_stt          NUMERIC(18,4) NOT NULL,
+		estimated_gst       NUMERIC(18,4) NOT NULL,
+		actual_gst          NUMERIC(18,4) NOT NULL,
+		status              VARCHAR(10) NOT NULL,
+		comment             TEXT NOT NULL DEFAULT '',
+		decided_at          TIMESTAMPTZ
+	)`,
+	// One open difference per trade date and symbol, so importing a note
+	// twice cannot post its adjustment twice. Duplicates left by earlier
+	// imports are rejected, keeping the approved or oldest row.
+	`UPDATE fee_differences d SET status = 'rejected', comment = 'duplicate import', decided_at = NOW()
+	WHERE d.status = 'pending' AND EXISTS (
+		SELECT 1 FROM fee_differences o
+		WHERE o.trade_date = d.trade_date AND o.stock_symbol = d.stock_symbol AND o.id <> d.id
+		  AND (o.status = 'approved' OR (o.status = 'pending' AND o.id < d.id))
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS fee_differences_open_idx ON fee_differences (trade_date, stock_symbol)
+	WHERE status IN ('pending', 'approved')`,
+
+	// Idempotency-Keys of POST /reward, stored by the transaction that
+	// creates the reward or approval request
+	`CREATE TABLE IF NOT EXISTS reward_idempotency_keys (
+		key        VARCHAR(255) PRIMARY KEY,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+
+	// Transactional outbox for domain events
+	`CREATE TABLE IF NOT EXISTS outbox_events (
+		id           BIGSERIAL PRIMARY KEY,
+		event_type   VARCHAR(50) NOT NULL,
+		user_id      INTEGER NOT NULL,
+		payload      JSONB NOT NULL,
+		created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		published_at TIMESTAMPTZ,
+		attempts     INTEGER NOT NULL DEFAULT 0,
+		last_error   TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE INDEX IF NOT EXISTS outbox_events_unpublished_idx ON outbox_events (id) WHERE published_at IS NULL`,
+	// Per-user event sequence. The counter row is locked by the writing
+	// transaction until it commits, so a user's events become visible in
+	// sequence order; rows written before it are numbered in ID order.
+	`ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS seq BIGINT`,
+	`ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS parked_at TIMESTAMPTZ`,
+	`UPDATE outbox_events e SET seq = n.seq
+	FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY id) AS seq FROM outbox_events) n
+	WHERE n.id = e.id AND e.seq IS NULL`,
+	`ALTER TABLE outbox_events ALTER COLUMN seq SET NOT NULL`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS outbox_events_user_seq_idx ON outbox_events (user_id, seq)`,
+	`CREATE TABLE IF NOT EXISTS outbox_sequences (
+		user_id  INTEGER PRIMARY KEY,
+		last_seq BIGINT NOT NULL
+	)`,
+	`INSERT INTO outbox_sequences (user_id, last_seq)
+	SELECT user_id, MAX(seq) FROM outbox_events GROUP BY user_id
+	ON CONFLICT (user_id) DO NOTHING`,
+
+	// Outbound webhooks
+	`CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+		id          SERIAL PRIMARY KEY,
+		url         TEXT NOT NULL,
+		event_types TEXT[] NOT NULL,
+		secret      VARCHAR(255) NOT NULL,
+		active      BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id               SERIAL PRIMARY KEY,
+		subscription_id  INTEGER NOT NULL REFERENCES webhook_subscriptions(id),
+		event_id         BIGINT NOT NULL REFERENCES outbox_events(id),
+		event_type       VARCHAR(50) NOT NULL,
+		payload          JSONB NOT NULL,
+		status           VARCHAR(10) NOT NULL,
+		attempts         INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_status_code INTEGER NOT NULL DEFAULT 0,
+		last_error       TEXT NOT NULL DEFAULT '',
+		created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		delivered_at     TIMESTAMPTZ,
+		UNIQUE (subscription_id, event_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending'`,
+
+	// In-app notification inbox
+	`CREATE TABLE IF NOT EXISTS notifications (
+		id         SERIAL PRIMARY KEY,
+		user_id    INTEGER NOT NULL REFERENCES users(id),
+		event_id   BIGINT UNIQUE REFERENCES outbox_events(id),
+		type       VARCHAR(50) NOT NULL,
+		title      VARCHAR(255) NOT NULL,
+		body       TEXT NOT NULL,
+		data       JSONB NOT NULL DEFAULT '{}',
+		read_at    TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS notifications_user_idx ON notifications (user_id, id DESC)`,
+	`CREATE TABLE IF NOT EXISTS notification_preferences (
+		user_id    INTEGER NOT NULL REFERENCES users(id),
+		channel    VARCHAR(20) NOT NULL,
+		enabled    BOOLEAN NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (user_id, channel)
+	)`,
+
+	// Authentication
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash VARCHAR(255)`,
+	`CREATE TABLE IF NOT EXISTS refresh_tokens (
+		id         SERIAL PRIMARY KEY,
+		user_id    INTEGER NOT NULL REFERENCES users(id),
+		token_hash CHAR(64) NOT NULL UNIQUE,
+		expires_at TIMESTAMPTZ NOT NULL,
+		revoked_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+
+	// Roles and server-to-server API keys
+	`CREATE TABLE IF NOT EXISTS user_roles (
+		user_id    INTEGER NOT NULL REFERENCES users(id),
+		role       VARCHAR(20) NOT NULL,
+		granted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (user_id, role)
+	)`,
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		id                SERIAL PRIMARY KEY,
+		name              VARCHAR(100) NOT NULL,
+		prefix            VARCHAR(8) NOT NULL,
+		key_hash          CHAR(64) NOT NULL UNIQUE,
+		roles             TEXT[] NOT NULL,
+		allowed_campaigns TEXT[] NOT NULL DEFAULT '{}',
+		max_quantity      NUMERIC(18,6) NOT NULL DEFAULT 0,
+		created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_used_at      TIMESTAMPTZ,
+		revoked_at        TIMESTAMPTZ
+	)`,
+	`ALTER TABLE rewards ADD COLUMN IF NOT EXISTS campaign VARCHAR(50) NOT NULL DEFAULT 'manual'`,
+
+	// Maker-checker approval of large manual rewards
+	`CREATE TABLE IF NOT EXISTS reward_approvals (
+		id                      SERIAL PRIMARY KEY,
+		user_id                 INTEGER NOT NULL REFERENCES users(id),
+		stock_symbol            VARCHAR(20) NOT NULL,
+		quantity                NUMERIC(18,6) NOT NULL,
+		campaign                VARCHAR(50) NOT NULL,
+		price_at_request        NUMERIC(18,4) NOT NULL,
+		value_inr               NUMERIC(18,4) NOT NULL,
+		status                  VARCHAR(10) NOT NULL DEFAULT 'pending',
+		requested_by_user_id    INTEGER REFERENCES users(id),
+		requested_by_api_key_id INTEGER REFERENCES api_keys(id),
+		requested_at            TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		decided_at              TIMESTAMPTZ,
+		reward_id               INTEGER REFERENCES rewards(id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS reward_approvals_status_idx ON reward_approvals (status, id DESC)`,
+	`CREATE TABLE IF NOT EXISTS reward_approval_decisions (
+		id               SERIAL PRIMARY KEY,
+		approval_id      INTEGER NOT NULL REFERENCES reward_approvals(id),
+		actor_user_id    INTEGER REFERENCES users(id),
+		actor_api_key_id INTEGER REFERENCES api_keys(id),
+		action           VARCHAR(10) NOT NULL,
+		comment          TEXT NOT NULL DEFAULT '',
+		created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+
+	// Hash-chained audit log. JSON (not JSONB) keeps values byte-for-byte
+	// so hashes can be recomputed, and the trigger makes the table append-only.
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id               BIGSERIAL PRIMARY KEY,
+		occurred_at      TIMESTAMPTZ NOT NULL,
+		actor_user_id    INTEGER,
+		actor_api_key_id INTEGER,
+		actor_ip         VARCHAR(45) NOT NULL DEFAULT '',
+		request_id       VARCHAR(64) NOT NULL DEFAULT '',
+		action           VARCHAR(50) NOT NULL,
+		entity_type      VARCHAR(50) NOT NULL,
+		entity_id        VARCHAR(50) NOT NULL,
+		before_value     JSON NOT NULL,
+		after_value      JSON NOT NULL,
+		prev_hash        CHAR(64) NOT NULL,
+		hash             CHAR(64) NOT NULL UNIQUE
+	)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log (entity_type, entity_id)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor_user_id, id DESC)`,
+	`CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'audit_log is append-only';
+	END;
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log`,
+	`CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+		FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only()`,
+	// Hash of the newest audit entry. Appends lock this single row at commit
+	// time; it starts from the existing chain, or the genesis hash.
+	`CREATE TABLE IF NOT EXISTS audit_chain_head (
+		id   SMALLINT PRIMARY KEY CHECK (id = 1),
+		hash CHAR(64) NOT NULL
+	)`,
+	`INSERT INTO audit_chain_head (id, hash)
+	SELECT 1, COALESCE((SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1), repeat('0', 64))
+	ON CONFLICT (id) DO NOTHING`,
+
+	// User profile and account status
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255) NOT NULL DEFAULT ''`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS phone VARCHAR(16) NOT NULL DEFAULT ''`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS pan VARCHAR(10) NOT NULL DEFAULT ''`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS demat_account_id VARCHAR(16) NOT NULL DEFAULT ''`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(12) NOT NULL DEFAULT 'active'`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (email) WHERE email <> ''`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS users_pan_key ON users (pan) WHERE pan <> ''`,
+
+	// KYC gating: rewards for unverified users are held, then released or expired
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS kyc_status VARCHAR(12) NOT NULL DEFAULT 'not_started'`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS kyc_updated_at TIMESTAMPTZ`,
+	`ALTER TABLE rewards ADD COLUMN IF NOT EXISTS kyc_hold BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE rewards ADD COLUMN IF NOT EXISTS kyc_expires_at TIMESTAMPTZ`,
+	`ALTER TABLE rewards ADD COLUMN IF NOT EXISTS kyc_released_at TIMESTAMPTZ`,
+	`CREATE INDEX IF NOT EXISTS rewards_kyc_hold_idx ON rewards (kyc_expires_at) WHERE kyc_hold`,
+
+	// Keyset pagination for GET /rewards
+	`CREATE INDEX IF NOT EXISTS rewards_user_time_idx ON rewards (user_id, rewarded_at, id)`,
+	`CREATE INDEX IF NOT EXISTS rewards_time_idx ON rewards (rewarded_at, id)`,
+
+	// Last observed price per trading session, used to value past holdings
+	`CREATE TABLE IF NOT EXISTS stock_prices (
+		symbol       VARCHAR(20) NOT NULL,
+		session_date DATE NOT NULL,
+		price        NUMERIC(18,4) NOT NULL,
+		observed_at  TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (symbol, session_date)
+	)`,
+
+	// Tax lots: one per reward, consumed oldest first by sales and reversals
+	`CREATE TABLE IF NOT EXISTS tax_lots (
+		id             SERIAL PRIMARY KEY,
+		reward_id      INTEGER NOT NULL UNIQUE REFERENCES rewards(id),
+		user_id        INTEGER NOT NULL REFERENCES users(id),
+		stock_symbol   VARCHAR(20) NOT NULL,
+		acquired_at    TIMESTAMPTZ NOT NULL,
+		quantity       NUMERIC(18,6) NOT NULL,
+		remaining      NUMERIC(18,6) NOT NULL CHECK (remaining >= 0),
+		cost_per_share NUMERIC(18,4) NOT NULL,
+		created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS tax_lots_open_idx ON tax_lots (user_id, stock_symbol, acquired_at, id) WHERE remaining > 0`,
+	`CREATE TABLE IF NOT EXISTS lot_disposals (
+		id          SERIAL PRIMARY KEY,
+		lot_id      INTEGER NOT NULL REFERENCES tax_lots(id),
+		kind        VARCHAR(10) NOT NULL CHECK (kind IN ('sell', 'reversal')),
+		reward_id   INTEGER REFERENCES rewards(id),
+		quantity    NUMERIC(18,6) NOT NULL CHECK (quantity > 0),
+		sale_price  NUMERIC(18,4) NOT NULL DEFAULT 0,
+		expenses    NUMERIC(18,4) NOT NULL DEFAULT 0,
+		disposed_at TIMESTAMPTZ NOT NULL,
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS lot_disposals_lot_idx ON lot_disposals (lot_id)`,
+	// Lots for rewards granted before lots existed, valued at the stored
+	// close of their grant session (zero when none was recorded)
+	`INSERT INTO tax_lots (reward_id, user_id, stock_symbol, acquired_at, quantity, remaining, cost_per_share)
+	SELECT r.id, r.user_id, r.stock_symbol, r.rewarded_at, r.quantity,
+	       CASE WHEN r.status = 'reversed' THEN 0 ELSE r.quantity END,
+	       COALESCE((
+	           SELECT p.price FROM stock_prices p
+	           WHERE p.symbol = r.stock_symbol
+	             AND p.session_date <= (r.rewarded_at AT TIME ZONE 'Asia/Kolkata')::date
+	           ORDER BY p.session_date DESC LIMIT 1
+	       ), 0)
+	FROM rewards r
+	ON CONFLICT (reward_id) DO NOTHING`,
+
+	// Grant-time fair market value, the recipient's taxable perquisite
+	`ALTER TABLE rewards ADD COLUMN IF NOT EXISTS grant_price NUMERIC(18,4)`,
+	`UPDATE rewards r SET grant_price = l.cost_per_share
+	FROM tax_lots l
+	WHERE l.reward_id = r.id AND r.grant_price IS NULL AND l.cost_per_share > 0`,
+
+	// Charges other than brokerage, STT and GST, and expense reporting by date
+	`ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS other_fees NUMERIC(18,4) NOT NULL DEFAULT 0`,
+	`CREATE INDEX IF NOT EXISTS ledger_entries_created_idx ON ledger_entries (created_at)`,
+
+	// Double-entry books. Amounts are signed (debit positive, credit
+	// negative) in a commodity: INR or a stock symbol, counted in units.
+	`CREATE TABLE IF NOT EXISTS accounts (
+		id         SERIAL PRIMARY KEY,
+		code       VARCHAR(100) NOT NULL UNIQUE,
+		name       VARCHAR(255) NOT NULL,
+		type       VARCHAR(10) NOT NULL CHECK (type IN ('asset', 'liability', 'equity', 'income', 'expense')),
+		user_id    INTEGER REFERENCES users(id),
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`INSERT INTO accounts (code, name, type) VALUES
+		('assets:cash',             'Cash',                                'asset'),
+		('assets:stock_inventory',  'Shares held for users',               'asset'),
+		('liabilities:tax_payable', 'STT and GST due on unsettled trades', 'liability'),
+		('expenses:rewards',        'Reward shares',                       'expense'),
+		('expenses:fees:brokerage', 'Brokerage',                           'expense'),
+		('expenses:fees:other',     'Exchange and other charges',          'expense'),
+		('expenses:taxes:stt',      'Securities transaction tax',          'expense'),
+		('expenses:taxes:gst',      'GST on brokerage',                    'expense'),
+		('equity:rounding',         'Rounding differences',                'equity')
+	ON CONFLICT (code) DO NOTHING`,
+	`CREATE TABLE IF NOT EXISTS journals (
+		id              SERIAL PRIMARY KEY,
+		kind            VARCHAR(20) NOT NULL,
+		reward_id       INTEGER REFERENCES rewards(id),
+		ledger_entry_id INTEGER UNIQUE REFERENCES ledger_entries(id),
+		description     TEXT NOT NULL DEFAULT '',
+		posted_at       TIMESTAMPTZ NOT NULL,
+		created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS journals_reward_idx ON journals (reward_id)`,
+	`CREATE INDEX IF NOT EXISTS journals_posted_idx ON journals (posted_at, id)`,
+	`CREATE TABLE IF NOT EXISTS journal_lines (
+		id         SERIAL PRIMARY KEY,
+		journal_id INTEGER NOT NULL REFERENCES journals(id),
+		account_id INTEGER NOT NULL REFERENCES accounts(id),
+		commodity  VARCHAR(20) NOT NULL,
+		amount     NUMERIC(18,6) NOT NULL CHECK (amount <> 0)
+	)`,
+	`CREATE INDEX IF NOT EXISTS journal_lines_journal_idx ON journal_lines (journal_id)`,
+	`CREATE INDEX IF NOT EXISTS journal_lines_account_idx ON journal_lines (account_id, commodity)`,
+	// Checked at commit so a journal's lines can be inserted one by one
+	`CREATE OR REPLACE FUNCTION journal_lines_balanced() RETURNS trigger AS $$
+	BEGIN
+		IF EXISTS (
+			SELECT 1 FROM journal_lines WHERE journal_id = NEW.journal_id
+			GROUP BY commodity HAVING SUM(amount) <> 0
+		) THEN
+			RAISE EXCEPTION 'journal % does not balance', NEW.journal_id;
+		END IF;
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS journal_lines_balanced ON journal_lines`,
+	`CREATE CONSTRAINT TRIGGER journal_lines_balanced AFTER INSERT OR UPDATE ON journal_lines
+		DEFERRABLE INITIALLY DEFERRED
+		FOR EACH ROW EXECUTE FUNCTION journal_lines_balanced()`,
+
+	// Reconciliation runs across rewards, ledger entries, journals and
+	// inventory, with one row per discrepancy found
+	`CREATE TABLE IF NOT EXISTS reconciliation_runs (
+		id           SERIAL PRIMARY KEY,
+		triggered_by VARCHAR(10) NOT NULL CHECK (triggered_by IN ('scheduled', 'manual')),
+		status       VARCHAR(15) NOT NULL CHECK (status IN ('clean', 'discrepancies', 'failed')),
+		started_at   TIMESTAMPTZ NOT NULL,
+		finished_at  TIMESTAMPTZ NOT NULL,
+		critical     INTEGER NOT NULL DEFAULT 0,
+		warnings     INTEGER NOT NULL DEFAULT 0,
+		error        TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE INDEX IF NOT EXISTS reconciliation_runs_started_idx ON reconciliation_runs (started_at DESC, id DESC)`,
+	`CREATE TABLE IF NOT EXISTS reconciliation_findings (
+		id           SERIAL PRIMARY KEY,
+		run_id       INTEGER NOT NULL REFERENCES reconciliation_runs(id),
+		check_name   VARCHAR(30) NOT NULL,
+		severity     VARCHAR(10) NOT NULL CHECK (severity IN ('critical', 'warning')),
+		reward_id    INTEGER REFERENCES rewards(id),
+		user_id      INTEGER REFERENCES users(id),
+		stock_symbol VARCHAR(20) NOT NULL DEFAULT '',
+		expected     NUMERIC(18,6) NOT NULL DEFAULT 0,
+		actual       NUMERIC(18,6) NOT NULL DEFAULT 0,
+		detail       TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE INDEX IF NOT EXISTS reconciliation_findings_run_idx ON reconciliation_findings (run_id)`,
+
+	// Per-user positions, kept in the same transaction as every reward
+	// and status change so portfolio reads do not scan all rewards
+	`CREATE TABLE IF NOT EXISTS holdings (
+		user_id      INTEGER NOT NULL REFERENCES users(id),
+		stock_symbol VARCHAR(20) NOT NULL,
+		quantity     NUMERIC(18,6) NOT NULL CHECK (quantity >= 0),
+		cost_basis   NUMERIC(18,4) NOT NULL DEFAULT 0,
+		updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (user_id, stock_symbol)
+	)`,
+	// Positions from rewards granted before the table existed. Rows only
+	// go missing for those, since every later reward upserts its row.
+	`INSERT INTO holdings (user_id, stock_symbol, quantity, cost_basis)
+	SELECT user_id, stock_symbol, SUM(quantity), SUM(quantity * COALESCE(grant_price, 0))
+	FROM rewards
+	WHERE status NOT IN ('failed', 'reversed')
+	GROUP BY user_id, stock_symbol
+	ON CONFLICT (user_id, stock_symbol) DO NOTHING`,
+	// Rewards the portfolio still reads: not yet settled, or on KYC hold
+	`CREATE INDEX IF NOT EXISTS rewards_user_open_idx ON rewards (user_id, stock_symbol)
+	WHERE status IN ('pending', 'ordered', 'purchased') OR kyc_hold`,
+}
+
+// Migrate applies the schema to the connected database
+func Migrate(conn *sqlx.DB) {
+	logger.Log.Info("Applying database schema...")
+	for _, stmt := range schema {
+		if _, err := conn.Exec(stmt); err != nil {
+			logger.Log.Fatalf("Failed to apply schema: %v", err)
+		}
+	}
+	logger.Log.Info("Database schema is up to date")
+}
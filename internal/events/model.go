@@ -0,0 +1,31 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Domain event types published through the outbox
+const (
+	RewardCreated     = "reward.created"
+	RewardReversed    = "reward.reversed"
+	UserRegistered    = "user.registered"
+	ReferralConverted = "referral.converted"
+
+	// Emitted by the corporate action and sell flows
+	CorporateActionApplied = "corporate_action.applied"
+	SellCompleted          = "sell.completed"
+)
+
+// Event is a row of the transactional outbox. UserID is the ordering key:
+// events for the same user are always published in Seq order.
+type Event struct {
+	ID          int64           `db:"id" json:"id"`
+	Type        string          `db:"event_type" json:"type"`
+	UserID      int             `db:"user_id" json:"user_id"`
+	Seq         int64           `db:"seq" json:"seq"`
+	Payload     json.RawMessage `db:"payload" json:"payload"`
+	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+	Attempts    int             `db:"attempts" json:"-"`
+	PublishedAt *time.Time      `db:"published_at" json:"-"`
+}
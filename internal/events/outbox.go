@@ -0,0 +1,34 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Write appends an event to the outbox inside the caller's transaction, so
+// it is committed or rolled back together with the domain change. The
+// user's sequence row stays locked until then, so a later event for the
+// same user cannot commit ahead of this one.
+func Write(ctx context.Context, tx *sqlx.Tx, eventType string, userID int, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var seq int64
+	err = tx.GetContext(ctx, &seq, `
+		INSERT INTO outbox_sequences (user_id, last_seq) VALUES ($1, 1)
+		ON CONFLICT (user_id) DO UPDATE SET last_seq = outbox_sequences.last_seq + 1
+		RETURNING last_seq
+	`, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO outbox_events (event_type, user_id, seq, payload) VALUES ($1, $2, $3, $4)
+	`, eventType, userID, seq, data)
+	return err
+}
@@ -0,0 +1,132 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/db"
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// Relay publishes unpublished outbox events to every sink, at least once
+type Relay struct {
+	db          *sqlx.DB
+	sinks       []Sink
+	batchSize   int
+	maxAttempts int
+}
+
+func NewRelay(db *sqlx.DB, sinks []Sink, batchSize, maxAttempts int) *Relay {
+	return &Relay{db: db, sinks: sinks, batchSize: batchSize, maxAttempts: maxAttempts}
+}
+
+// RunOnce publishes one batch and returns how many events were delivered.
+// Each user's events go out in sequence order. If any sink fails for an
+// event, that user's later events are held back until the next run; an
+// event that has failed maxAttempts times is parked so it stops holding
+// them back.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var leader bool
+	if err := tx.GetContext(ctx, &leader, `SELECT pg_try_advisory_xact_lock($1)`, db.LockOutboxRelay); err != nil {
+		return 0, err
+	}
+	if !leader {
+		return 0, nil
+	}
+
+	// The oldest events by ID, in per-user sequence order. Write allocates
+	// a user's IDs and sequence numbers under the same row lock, so a
+	// batch never holds an event without that user's earlier ones.
+	pending := []Event{}
+	err = tx.SelectContext(ctx, &pending, `
+		SELECT id, event_type, user_id, seq, payload, created_at, attempts, published_at
+		FROM (
+			SELECT id, event_type, user_id, seq, payload, created_at, attempts, published_at
+			FROM outbox_events
+			WHERE published_at IS NULL AND parked_at IS NULL
+			ORDER BY id
+			LIMIT $1
+		) e
+		ORDER BY user_id, seq
+	`, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	blocked := map[int]bool{}
+	for _, event := range pending {
+		if blocked[event.UserID] {
+			continue
+		}
+
+		if err := r.publish(ctx, event); err != nil {
+			entry := logger.Log.WithFields(map[string]interface{}{
+				"event_id": event.ID,
+				"user_id":  event.UserID,
+				"attempts": event.Attempts + 1,
+			})
+			if event.Attempts+1 >= r.maxAttempts {
+				entry.Errorf("Parking event after repeated publish failures: %v", err)
+				_, err = tx.ExecContext(ctx, `
+					UPDATE outbox_events SET attempts = attempts + 1, last_error = $2, parked_at = NOW() WHERE id = $1
+				`, event.ID, err.Error())
+				if err != nil {
+					return published, err
+				}
+				continue
+			}
+
+			blocked[event.UserID] = true
+			entry.Warnf("Failed to publish event: %v", err)
+			_, err = tx.ExecContext(ctx, `
+				UPDATE outbox_events SET attempts = attempts + 1, last_error = $2 WHERE id = $1
+			`, event.ID, err.Error())
+			if err != nil {
+				return published, err
+			}
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE outbox_events SET published_at = NOW(), attempts = attempts + 1, last_error = '' WHERE id = $1
+		`, event.ID)
+		if err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, tx.Commit()
+}
+
+func (r *Relay) publish(ctx context.Context, event Event) error {
+	for _, sink := range r.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			return fmt.Errorf("%s sink: %w", sink.Name(), err)
+		}
+	}
+	return nil
+}
+
+// StartRelay polls the outbox on the given interval
+func StartRelay(relay *Relay, interval time.Duration) {
+	logger.Log.WithField("interval", interval.String()).Info("📨 Outbox relay started")
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			if _, err := relay.RunOnce(context.Background()); err != nil {
+				logger.Log.Errorf("Outbox relay run failed: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// Sink is a destination the relay publishes outbox events to. Publish may
+// be called more than once for the same event.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event Event) error
+}
+
+// LogSink writes events to the application log
+type LogSink struct{}
+
+func NewLogSink() *LogSink {
+	return &LogSink{}
+}
+
+func (s *LogSink) Name() string {
+	return "log"
+}
+
+func (s *LogSink) Publish(ctx context.Context, event Event) error {
+	logger.Log.WithFields(map[string]interface{}{
+		"event_id":   event.ID,
+		"event_type": event.Type,
+		"user_id":    event.UserID,
+		"payload":    string(event.Payload),
+	}).Info("Domain event published")
+	return nil
+}
+
+// RedisStreamSink appends events to a Redis stream
+type RedisStreamSink struct {
+	client *redis.Client
+	stream string
+}
+
+func NewRedisStreamSink(client *redis.Client, stream string) *RedisStreamSink {
+	return &RedisStreamSink{client: client, stream: stream}
+}
+
+func (s *RedisStreamSink) Name() string {
+	return "redis"
+}
+
+func (s *RedisStreamSink) Publish(ctx context.Context, event Event) error {
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		Values: map[string]interface{}{
+			"event_id":   event.ID,
+			"event_type": event.Type,
+			"user_id":    event.UserID,
+			"payload":    string(event.Payload),
+			"created_at": event.CreatedAt.Format("2006-01-02T15:04:05.000Z07:00"),
+		},
+	}).Err()
+}
@@ -0,0 +1,92 @@
+package holdings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrInsufficientHoldings = errors.New("not enough shares held")
+
+const holdingColumns = `user_id, stock_symbol, quantity, cost_basis, updated_at`
+
+// Add credits shares to a user's holding inside the caller's transaction,
+// opening the holding on first use. The upsert locks the row until commit.
+func Add(ctx context.Context, tx *sqlx.Tx, userID int, symbol string, quantity, cost float64) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO holdings (user_id, stock_symbol, quantity, cost_basis, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (user_id, stock_symbol) DO UPDATE
+		SET quantity = holdings.quantity + EXCLUDED.quantity,
+		    cost_basis = holdings.cost_basis + EXCLUDED.cost_basis,
+		    updated_at = NOW()
+	`, userID, symbol, roundQty(quantity), roundINR(cost))
+	return err
+}
+
+// Lock loads a user's holding with a row lock for the rest of the
+// transaction, so a sell can check the balance it is about to spend. A
+// symbol the user never held comes back empty.
+func Lock(ctx context.Context, tx *sqlx.Tx, userID int, symbol string) (Holding, error) {
+	h := Holding{UserID: userID, StockSymbol: symbol}
+	err := tx.GetContext(ctx, &h, `
+		SELECT `+holdingColumns+`
+		FROM holdings
+		WHERE user_id = $1 AND stock_symbol = $2
+		FOR UPDATE
+	`, userID, symbol)
+	if errors.Is(err, sql.ErrNoRows) {
+		return h, nil
+	}
+	return h, err
+}
+
+// Remove debits shares and their cost from a locked holding. It fails
+// rather than let the holding go negative.
+func Remove(ctx context.Context, tx *sqlx.Tx, userID int, symbol string, quantity, cost float64) error {
+	h, err := Lock(ctx, tx, userID, symbol)
+	if err != nil {
+		return err
+	}
+	quantity = roundQty(quantity)
+	if h.Quantity < quantity {
+		return fmt.Errorf("%w: user %d holds %v %s, %v requested", ErrInsufficientHoldings, userID, h.Quantity, symbol, quantity)
+	}
+
+	// The last shares out take whatever cost is left, so no residue remains
+	cost = roundINR(cost)
+	if h.Quantity == quantity {
+		cost = h.CostBasis
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE holdings
+		SET quantity = quantity - $3, cost_basis = cost_basis - $4, updated_at = NOW()
+		WHERE user_id = $1 AND stock_symbol = $2
+	`, userID, symbol, quantity, cost)
+	return err
+}
+
+// ForUser lists the symbols a user currently holds
+func ForUser(ctx context.Context, q sqlx.QueryerContext, userID int) ([]Holding, error) {
+	held := []Holding{}
+	err := sqlx.SelectContext(ctx, q, &held, `
+		SELECT `+holdingColumns+`
+		FROM holdings
+		WHERE user_id = $1 AND quantity > 0
+		ORDER BY stock_symbol
+	`, userID)
+	return held, err
+}
+
+// Quantities carry six decimals and INR four, like the rewards and ledger
+func roundQty(v float64) float64 {
+	return math.Round(v*1000000) / 1000000
+}
+
+func roundINR(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
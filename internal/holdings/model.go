@@ -0,0 +1,14 @@
+package holdings
+
+import "time"
+
+// Holding is a user's position in one symbol: every reward that has not
+// failed or been reversed, less anything sold. CostBasis is the grant
+// value of those shares.
+type Holding struct {
+	UserID      int       `db:"user_id" json:"user_id"`
+	StockSymbol string    `db:"stock_symbol" json:"symbol"`
+	Quantity    float64   `db:"quantity" json:"quantity"`
+	CostBasis   float64   `db:"cost_basis" json:"cost_basis"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
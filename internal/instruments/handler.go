@@ -0,0 +1,105 @@
+package instruments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+type InstrumentHandler struct {
+	service *InstrumentService
+}
+
+func NewInstrumentHandler(service *InstrumentService) *InstrumentHandler {
+	return &InstrumentHandler{service: service}
+}
+
+// ListInstruments handles GET /admin/instruments?status=active
+func (h *InstrumentHandler) ListInstruments(c *gin.Context) {
+	instruments, err := h.service.List(context.Background(), c.Query("status"))
+	if err != nil {
+		logger.Log.Errorf("Failed to list instruments: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list instruments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"instruments": instruments})
+}
+
+func (h *InstrumentHandler) GetInstrument(c *gin.Context) {
+	inst, err := h.service.Get(context.Background(), c.Param("symbol"))
+	if err != nil {
+		h.writeError(c, err, "failed to fetch instrument")
+		return
+	}
+
+	c.JSON(http.StatusOK, inst)
+}
+
+func (h *InstrumentHandler) CreateInstrument(c *gin.Context) {
+	var req InstrumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Log.Warnf("Invalid instrument request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	inst, err := h.service.Create(c.Request.Context(), req)
+	if err != nil {
+		h.writeError(c, err, "failed to create instrument")
+		return
+	}
+
+	logger.Log.WithField("symbol", inst.Symbol).Info("Instrument created")
+	c.JSON(http.StatusCreated, inst)
+}
+
+func (h *InstrumentHandler) UpdateInstrument(c *gin.Context) {
+	var req InstrumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Log.Warnf("Invalid instrument request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	inst, err := h.service.Update(c.Request.Context(), c.Param("symbol"), req)
+	if err != nil {
+		h.writeError(c, err, "failed to update instrument")
+		return
+	}
+
+	logger.Log.WithField("symbol", inst.Symbol).Info("Instrument updated")
+	c.JSON(http.StatusOK, inst)
+}
+
+func (h *InstrumentHandler) DeleteInstrument(c *gin.Context) {
+	inst, err := h.service.Deactivate(c.Request.Context(), c.Param("symbol"))
+	if err != nil {
+		h.writeError(c, err, "failed to deactivate instrument")
+		return
+	}
+
+	logger.Log.WithField("symbol", inst.Symbol).Info("Instrument deactivated")
+	c.JSON(http.StatusOK, inst)
+}
+
+func (h *InstrumentHandler) writeError(c *gin.Context, err error, msg string) {
+	var validationErr *ValidationError
+	var pqErr *pq.Error
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		c.JSON(http.StatusConflict, gin.H{"error": "instrument already exists"})
+	default:
+		logger.Log.Errorf("%s: %v", msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
@@ -0,0 +1,30 @@
+package instruments
+
+import "time"
+
+const (
+	StatusActive   = "active"
+	StatusInactive = "inactive"
+)
+
+type Instrument struct {
+	Symbol       string    `db:"symbol" json:"symbol"`
+	ISIN         string    `db:"isin" json:"isin"`
+	Exchange     string    `db:"exchange" json:"exchange"`
+	Name         string    `db:"name" json:"name"`
+	Sector       string    `db:"sector" json:"sector"`
+	LotPrecision int       `db:"lot_precision" json:"lot_precision"`
+	Status       string    `db:"status" json:"status"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type InstrumentRequest struct {
+	Symbol       string `json:"symbol"`
+	ISIN         string `json:"isin" binding:"required"`
+	Exchange     string `json:"exchange" binding:"required"`
+	Name         string `json:"name" binding:"required"`
+	Sector       string `json:"sector"`
+	LotPrecision *int   `json:"lot_precision"`
+	Status       string `json:"status"`
+}
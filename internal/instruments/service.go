@@ -0,0 +1,255 @@
+package instruments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrNotFound          = errors.New("instrument not found")
+	ErrInactive          = errors.New("instrument is not active")
+	ErrNoActive          = errors.New("no active instruments")
+	ErrQuantityPrecision = errors.New("quantity exceeds instrument lot precision")
+	ErrInvalidQuantity   = errors.New("quantity must be positive")
+)
+
+var (
+	symbolPattern = regexp.MustCompile(`^[A-Z0-9&-]{1,20}$`)
+	isinPattern   = regexp.MustCompile(`^IN[A-Z0-9]{9}[0-9]$`)
+)
+
+var exchanges = map[string]bool{"NSE": true, "BSE": true}
+
+// InstrumentService manages the instrument master and symbol validation
+type InstrumentService struct {
+	db *sqlx.DB
+}
+
+func NewInstrumentService(db *sqlx.DB) *InstrumentService {
+	return &InstrumentService{db: db}
+}
+
+const instrumentColumns = `symbol, isin, exchange, name, sector, lot_precision, status, created_at, updated_at`
+
+// List returns all instruments, optionally filtered by status
+func (s *InstrumentService) List(ctx context.Context, status string) ([]Instrument, error) {
+	instruments := []Instrument{}
+
+	query := `SELECT ` + instrumentColumns + ` FROM instruments`
+	args := []interface{}{}
+	if status != "" {
+		query += ` WHERE status = $1`
+		args = append(args, status)
+	}
+	query += ` ORDER BY symbol`
+
+	if err := s.db.SelectContext(ctx, &instruments, query, args...); err != nil {
+		return nil, err
+	}
+	return instruments, nil
+}
+
+func (s *InstrumentService) Get(ctx context.Context, symbol string) (Instrument, error) {
+	var inst Instrument
+	err := s.db.GetContext(ctx, &inst,
+		`SELECT `+instrumentColumns+` FROM instruments WHERE symbol = $1`,
+		NormalizeSymbol(symbol),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return inst, ErrNotFound
+	}
+	return inst, err
+}
+
+func (s *InstrumentService) Create(ctx context.Context, req InstrumentRequest) (Instrument, error) {
+	var inst Instrument
+
+	req.Symbol = NormalizeSymbol(req.Symbol)
+	if err := validateRequest(req); err != nil {
+		return inst, err
+	}
+
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return inst, err
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &inst, `
+		INSERT INTO instruments (symbol, isin, exchange, name, sector, lot_precision, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+instrumentColumns,
+		req.Symbol, strings.ToUpper(req.ISIN), strings.ToUpper(req.Exchange),
+		req.Name, req.Sector, lotPrecision(req), statusOrDefault(req.Status),
+	)
+	if err != nil {
+		return inst, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{
+		Action:     audit.ActionInstrumentCreated,
+		EntityType: "instrument",
+		EntityID:   inst.Symbol,
+		After:      inst,
+	})
+	if err != nil {
+		return inst, err
+	}
+	return inst, tx.Commit()
+}
+
+func (s *InstrumentService) Update(ctx context.Context, symbol string, req InstrumentRequest) (Instrument, error) {
+	var inst Instrument
+
+	req.Symbol = NormalizeSymbol(symbol)
+	if err := validateRequest(req); err != nil {
+		return inst, err
+	}
+
+	return s.update(ctx, req.Symbol, `
+		UPDATE instruments
+		SET isin = $2, exchange = $3, name = $4, sector = $5,
+		    lot_precision = $6, status = $7, updated_at = NOW()
+		WHERE symbol = $1
+		RETURNING `+instrumentColumns,
+		strings.ToUpper(req.ISIN), strings.ToUpper(req.Exchange),
+		req.Name, req.Sector, lotPrecision(req), statusOrDefault(req.Status),
+	)
+}
+
+// Deactivate marks an instrument inactive. Rows are never deleted because
+// existing rewards and ledger entries still reference the symbol.
+func (s *InstrumentService) Deactivate(ctx context.Context, symbol string) (Instrument, error) {
+	return s.update(ctx, NormalizeSymbol(symbol), `
+		UPDATE instruments
+		SET status = $2, updated_at = NOW()
+		WHERE symbol = $1
+		RETURNING `+instrumentColumns,
+		StatusInactive,
+	)
+}
+
+// update runs an UPDATE ... RETURNING for one symbol ($1) and records the
+// before and after rows in the audit log
+func (s *InstrumentService) update(ctx context.Context, symbol, query string, args ...interface{}) (Instrument, error) {
+	var before, inst Instrument
+
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return inst, err
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &before,
+		`SELECT `+instrumentColumns+` FROM instruments WHERE symbol = $1 FOR UPDATE`, symbol)
+	if errors.Is(err, sql.ErrNoRows) {
+		return inst, ErrNotFound
+	}
+	if err != nil {
+		return inst, err
+	}
+
+	if err := tx.GetContext(ctx, &inst, query, append([]interface{}{symbol}, args...)...); err != nil {
+		return inst, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{
+		Action:     audit.ActionInstrumentUpdated,
+		EntityType: "instrument",
+		EntityID:   symbol,
+		Before:     before,
+		After:      inst,
+	})
+	if err != nil {
+		return inst, err
+	}
+	return inst, tx.Commit()
+}
+
+// ValidateReward checks that a symbol is an active instrument and that the
+// quantity fits within its lot precision
+func (s *InstrumentService) ValidateReward(ctx context.Context, symbol string, quantity float64) (Instrument, error) {
+	inst, err := s.Get(ctx, symbol)
+	if err != nil {
+		return inst, err
+	}
+	if inst.Status != StatusActive {
+		return inst, ErrInactive
+	}
+
+	if !(quantity > 0) || math.IsInf(quantity, 1) {
+		return inst, ErrInvalidQuantity
+	}
+	scale := math.Pow(10, float64(inst.LotPrecision))
+	if math.Abs(quantity*scale-math.Round(quantity*scale)) > 1e-6 {
+		return inst, ErrQuantityPrecision
+	}
+	return inst, nil
+}
+
+// RandomActive picks a random active instrument for onboarding and referral rewards
+func (s *InstrumentService) RandomActive(ctx context.Context) (Instrument, error) {
+	var inst Instrument
+	err := s.db.GetContext(ctx, &inst,
+		`SELECT `+instrumentColumns+` FROM instruments WHERE status = $1 ORDER BY random() LIMIT 1`,
+		StatusActive,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return inst, ErrNoActive
+	}
+	return inst, err
+}
+
+func NormalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// ValidationError is returned when an instrument request is malformed
+type ValidationError struct {
+	Field string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid instrument %s", e.Field)
+}
+
+func validateRequest(req InstrumentRequest) error {
+	if !symbolPattern.MatchString(req.Symbol) {
+		return &ValidationError{Field: "symbol"}
+	}
+	if !isinPattern.MatchString(strings.ToUpper(req.ISIN)) {
+		return &ValidationError{Field: "isin"}
+	}
+	if !exchanges[strings.ToUpper(req.Exchange)] {
+		return &ValidationError{Field: "exchange"}
+	}
+	if req.LotPrecision != nil && (*req.LotPrecision < 0 || *req.LotPrecision > 6) {
+		return &ValidationError{Field: "lot_precision"}
+	}
+	if req.Status != "" && req.Status != StatusActive && req.Status != StatusInactive {
+		return &ValidationError{Field: "status"}
+	}
+	return nil
+}
+
+func lotPrecision(req InstrumentRequest) int {
+	if req.LotPrecision == nil {
+		return 6
+	}
+	return *req.LotPrecision
+}
+
+func statusOrDefault(status string) string {
+	if status == "" {
+		return StatusActive
+	}
+	return status
+}
@@ -0,0 +1,141 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	service *NotificationService
+}
+
+func NewNotificationHandler(service *NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+// GetNotifications handles GET /users/:id/notifications?unread=true&limit=20&before_id=
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	beforeID, err := strconv.Atoi(c.DefaultQuery("before_id", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before_id"})
+		return
+	}
+
+	inbox, err := h.service.GetInbox(context.Background(), userID, c.Query("unread") == "true", beforeID, limit)
+	if err != nil {
+		logger.Log.Errorf("Failed to fetch notifications for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, inbox)
+}
+
+// MarkRead handles POST /users/:id/notifications/:notificationId/read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	notificationID, err := strconv.Atoi(c.Param("notificationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notification id"})
+		return
+	}
+
+	err = h.service.MarkRead(context.Background(), userID, notificationID)
+	if errors.Is(err, ErrNotificationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Failed to mark notification %d read: %v", notificationID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to mark notification read"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MarkAllRead handles POST /users/:id/notifications/read-all
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	updated, err := h.service.MarkAllRead(context.Background(), userID)
+	if err != nil {
+		logger.Log.Errorf("Failed to mark notifications read for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to mark notifications read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked_read": updated})
+}
+
+// GetPreferences handles GET /users/:id/notification-preferences
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	prefs, err := h.service.GetPreferences(context.Background(), userID)
+	if err != nil {
+		logger.Log.Errorf("Failed to fetch notification preferences for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "preferences": prefs})
+}
+
+// UpdatePreferences handles PUT /users/:id/notification-preferences
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req PreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Log.Warnf("Invalid notification preferences request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	prefs, err := h.service.UpdatePreferences(context.Background(), userID, req.Preferences)
+	if errors.Is(err, ErrUnknownChannel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Failed to update notification preferences for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update preferences"})
+		return
+	}
+
+	logger.Log.WithField("user_id", userID).Info("Notification preferences updated")
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "preferences": prefs})
+}
@@ -0,0 +1,48 @@
+package notifications
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Delivery channels a user can opt in to. The in-app inbox is always on;
+// email and push are stored now for future senders.
+const (
+	ChannelInApp = "in_app"
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+)
+
+var channels = []string{ChannelInApp, ChannelEmail, ChannelPush}
+
+type Notification struct {
+	ID        int             `db:"id" json:"id"`
+	UserID    int             `db:"user_id" json:"user_id"`
+	Type      string          `db:"type" json:"type"`
+	Title     string          `db:"title" json:"title"`
+	Body      string          `db:"body" json:"body"`
+	Data      json.RawMessage `db:"data" json:"data"`
+	ReadAt    *time.Time      `db:"read_at" json:"read_at"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+type Inbox struct {
+	UserID        int            `json:"user_id"`
+	UnreadCount   int            `json:"unread_count"`
+	Notifications []Notification `json:"notifications"`
+}
+
+type Preference struct {
+	Channel   string     `db:"channel" json:"channel"`
+	Enabled   bool       `db:"enabled" json:"enabled"`
+	UpdatedAt *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+}
+
+type PreferencesRequest struct {
+	Preferences []PreferenceInput `json:"preferences" binding:"required"`
+}
+
+type PreferenceInput struct {
+	Channel string `json:"channel" binding:"required"`
+	Enabled bool   `json:"enabled"`
+}
@@ -0,0 +1,188 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/angad363/stocky-assignment/internal/events"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrUnknownChannel       = errors.New("unknown notification channel")
+)
+
+type NotificationService struct {
+	db *sqlx.DB
+}
+
+func NewNotificationService(db *sqlx.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// CreateFromEvent adds an inbox item for an outbox event. Re-delivery of the
+// same event is ignored.
+func (s *NotificationService) CreateFromEvent(ctx context.Context, event events.Event) error {
+	title, body, ok := render(event)
+	if !ok {
+		return nil
+	}
+	if event.Type == events.RewardCreated {
+		referral, err := s.fromReferral(ctx, event)
+		if err != nil || referral {
+			return err
+		}
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO notifications (user_id, event_id, type, title, body, data, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (event_id) DO NOTHING
+	`, event.UserID, event.ID, event.Type, title, body, event.Payload, event.CreatedAt)
+	return err
+}
+
+// fromReferral reports whether a reward.created event belongs to a referral.
+// The referral flow writes referral.converted for the same reward in the
+// same transaction, and that event announces it, so the reward itself is
+// not announced twice.
+func (s *NotificationService) fromReferral(ctx context.Context, event events.Event) (bool, error) {
+	var created struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(event.Payload, &created); err != nil || created.ID == 0 {
+		return false, nil
+	}
+
+	var referral bool
+	err := s.db.GetContext(ctx, &referral, `
+		SELECT EXISTS (
+			SELECT 1 FROM outbox_events
+			WHERE user_id = $1 AND event_type = $2 AND (payload->'reward'->>'id')::int = $3
+		)
+	`, event.UserID, events.ReferralConverted, created.ID)
+	return referral, err
+}
+
+// GetInbox lists a user's notifications newest first. beforeID pages
+// backwards from an earlier response.
+func (s *NotificationService) GetInbox(ctx context.Context, userID int, unreadOnly bool, beforeID, limit int) (Inbox, error) {
+	inbox := Inbox{UserID: userID, Notifications: []Notification{}}
+
+	query := `
+		SELECT id, user_id, type, title, body, data, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1`
+	args := []interface{}{userID}
+	if unreadOnly {
+		query += ` AND read_at IS NULL`
+	}
+	if beforeID > 0 {
+		args = append(args, beforeID)
+		query += fmt.Sprintf(` AND id < $%d`, len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	if err := s.db.SelectContext(ctx, &inbox.Notifications, query, args...); err != nil {
+		return inbox, err
+	}
+
+	err := s.db.GetContext(ctx, &inbox.UnreadCount, `
+		SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL
+	`, userID)
+	return inbox, err
+}
+
+func (s *NotificationService) MarkRead(ctx context.Context, userID, notificationID int) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2
+	`, notificationID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID int) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetPreferences returns every channel, filling in defaults for channels
+// the user never changed (in-app on, others off)
+func (s *NotificationService) GetPreferences(ctx context.Context, userID int) ([]Preference, error) {
+	stored := []Preference{}
+	err := s.db.SelectContext(ctx, &stored, `
+		SELECT channel, enabled, updated_at FROM notification_preferences WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	byChannel := map[string]Preference{}
+	for _, p := range stored {
+		byChannel[p.Channel] = p
+	}
+
+	prefs := make([]Preference, 0, len(channels))
+	for _, ch := range channels {
+		p, ok := byChannel[ch]
+		if !ok {
+			p = Preference{Channel: ch, Enabled: ch == ChannelInApp}
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs, nil
+}
+
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID int, inputs []PreferenceInput) ([]Preference, error) {
+	for _, in := range inputs {
+		if !validChannel(in.Channel) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownChannel, in.Channel)
+		}
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, in := range inputs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO notification_preferences (user_id, channel, enabled, updated_at)
+			VALUES ($1, $2, $3, NOW())
+			ON CONFLICT (user_id, channel) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = NOW()
+		`, userID, in.Channel, in.Enabled)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetPreferences(ctx, userID)
+}
+
+func validChannel(channel string) bool {
+	for _, ch := range channels {
+		if ch == channel {
+			return true
+		}
+	}
+	return false
+}
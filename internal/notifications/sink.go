@@ -0,0 +1,24 @@
+package notifications
+
+import (
+	"context"
+
+	"github.com/angad363/stocky-assignment/internal/events"
+)
+
+// Sink creates inbox items from outbox events
+type Sink struct {
+	service *NotificationService
+}
+
+func NewSink(service *NotificationService) *Sink {
+	return &Sink{service: service}
+}
+
+func (s *Sink) Name() string {
+	return "notifications"
+}
+
+func (s *Sink) Publish(ctx context.Context, event events.Event) error {
+	return s.service.CreateFromEvent(ctx, event)
+}
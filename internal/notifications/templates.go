@@ -0,0 +1,50 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/angad363/stocky-assignment/internal/events"
+)
+
+// render builds the inbox title and body for an event. Events without a
+// template do not produce notifications.
+func render(event events.Event) (string, string, bool) {
+	var p struct {
+		StockSymbol string  `json:"stock_symbol"`
+		Quantity    float64 `json:"quantity"`
+		Referral    struct {
+			FriendName string `json:"friend_name"`
+		} `json:"referral"`
+		Reward struct {
+			StockSymbol string  `json:"stock_symbol"`
+			Quantity    float64 `json:"quantity"`
+		} `json:"reward"`
+		Description string  `json:"description"`
+		Symbol      string  `json:"symbol"`
+		ProceedsINR float64 `json:"proceeds_inr"`
+	}
+	_ = json.Unmarshal(event.Payload, &p)
+
+	switch event.Type {
+	case events.RewardCreated:
+		return "You received stock!",
+			fmt.Sprintf("%s share(s) of %s were added to your portfolio.", qty(p.Quantity), p.StockSymbol), true
+	case events.ReferralConverted:
+		return "Referral successful",
+			fmt.Sprintf("%s joined through your referral. You earned %s share(s) of %s.",
+				p.Referral.FriendName, qty(p.Reward.Quantity), p.Reward.StockSymbol), true
+	case events.CorporateActionApplied:
+		return "Corporate action applied",
+			fmt.Sprintf("Your %s holding was adjusted: %s.", p.Symbol, p.Description), true
+	case events.SellCompleted:
+		return "Sale completed",
+			fmt.Sprintf("Your %s shares were sold for ₹%.2f.", p.Symbol, p.ProceedsINR), true
+	}
+	return "", "", false
+}
+
+func qty(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
@@ -0,0 +1,44 @@
+package price
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SaveQuote records a quote against its trading session. Later quotes of
+// the same session replace earlier ones, so the stored price ends up being
+// the session's close.
+func SaveQuote(ctx context.Context, db sqlx.ExecerContext, quote PriceResponse) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO stock_prices (symbol, session_date, price, observed_at)
+		VALUES ($1, ($2::timestamptz AT TIME ZONE 'Asia/Kolkata')::date, $3, $2)
+		ON CONFLICT (symbol, session_date) DO UPDATE
+		SET price = EXCLUDED.price, observed_at = EXCLUDED.observed_at
+		WHERE stock_prices.observed_at <= EXCLUDED.observed_at
+	`, quote.Symbol, quote.AsOf, quote.Price)
+	return err
+}
+
+// ClosingPrice returns the last recorded price of a symbol on or before the
+// given session date. ok is false when no price was recorded by then.
+func ClosingPrice(ctx context.Context, db sqlx.QueryerContext, symbol string, session time.Time) (PriceResponse, bool, error) {
+	resp := PriceResponse{Symbol: symbol}
+	err := db.QueryRowxContext(ctx, `
+		SELECT price, observed_at
+		FROM stock_prices
+		WHERE symbol = $1 AND session_date <= $2::date
+		ORDER BY session_date DESC
+		LIMIT 1
+	`, symbol, session.Format("2006-01-02")).Scan(&resp.Price, &resp.AsOf)
+	if errors.Is(err, sql.ErrNoRows) {
+		return resp, false, nil
+	}
+	if err != nil {
+		return resp, false, err
+	}
+	return resp, true, nil
+}
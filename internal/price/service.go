@@ -6,12 +6,14 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/angad363/stocky-assignment/internal/calendar"
 	"github.com/redis/go-redis/v9"
 )
 
 // PriceService holds a redis client for caching stock prices
 type PriceService struct {
-	cache redisClient
+	cache    redisClient
+	calendar *calendar.Calendar
 }
 
 // redisClient defines the methods we use from the redis.Client
@@ -22,14 +24,16 @@ type redisClient interface {
 
 // PriceResponse defines our JSON response model
 type PriceResponse struct {
-	Symbol string  `json:"symbol"`
-	Price  float64 `json:"price"`
+	Symbol string    `json:"symbol"`
+	Price  float64   `json:"price"`
+	AsOf   time.Time `json:"as_of"` // last trading session the price belongs to
 }
 
 // NewPriceService creates a new instance of PriceService
-func NewPriceService(client *redis.Client) *PriceService {
+func NewPriceService(client *redis.Client, cal *calendar.Calendar) *PriceService {
 	return &PriceService{
-		cache: client,
+		cache:    client,
+		calendar: cal,
 	}
 }
 
@@ -51,6 +55,7 @@ func (p *PriceService) GetStockPrice(symbol string) (PriceResponse, error) {
 	resp = PriceResponse{
 		Symbol: symbol,
 		Price:  price,
+		AsOf:   p.calendar.PriceAsOf(time.Now()),
 	}
 
 	// 3. Store in Redis for 10 minutes
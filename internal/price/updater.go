@@ -1,6 +1,7 @@
 package price
 
 import (
+	"context"
 	"time"
 
 	"github.com/angad363/stocky-assignment/pkg/logger"
@@ -21,7 +22,7 @@ func StartPriceUpdater(service *PriceService, conn *sqlx.DB) {
 func updateAllHoldings(service *PriceService, conn *sqlx.DB) {
 	logger.Log.Info("🔄 Starting hourly stock price update...")
 
-	rows, err := conn.Query("SELECT DISTINCT stock_symbol FROM ledger_entries")
+	rows, err := conn.Query("SELECT stock_symbol FROM ledger_entries UNION SELECT stock_symbol FROM rewards")
 	if err != nil {
 		logger.Log.Errorf("Error fetching symbols: %v", err)
 		return
@@ -42,6 +43,10 @@ func updateAllHoldings(service *PriceService, conn *sqlx.DB) {
 			logger.Log.WithField("symbol", symbol).Errorf("Failed to update price: %v", err)
 			continue
 		}
+		// Kept per session so statements can value holdings at past closes
+		if err := SaveQuote(context.Background(), conn, priceResp); err != nil {
+			logger.Log.WithField("symbol", symbol).Errorf("Failed to record stock price: %v", err)
+		}
 		logger.Log.WithFields(map[string]interface{}{
 			"symbol": symbol,
 			"price":  priceResp.Price,
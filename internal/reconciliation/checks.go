@@ -0,0 +1,221 @@
+package reconciliation
+
+import (
+	"context"
+
+	"github.com/angad363/stocky-assignment/internal/accounting"
+	"github.com/angad363/stocky-assignment/internal/reward"
+	"github.com/jmoiron/sqlx"
+)
+
+// check is one reconciliation query. Each row it returns is a finding;
+// severity is filled in by the check unless the query sets it.
+type check struct {
+	name     string
+	severity string
+	query    string
+	args     []interface{}
+}
+
+// Units the broker has allocated to each reward so far; rewards still
+// pending or ordered may be partly filled
+const allocatedCTE = `
+	allocated AS (
+		SELECT reward_id, SUM(quantity) AS units
+		FROM broker_allocations
+		GROUP BY reward_id
+	)`
+
+var checks = []check{
+	{
+		name:     CheckRewardUnbalanced,
+		severity: SeverityCritical,
+		query: `
+			SELECT j.reward_id, r.user_id, l.commodity AS stock_symbol, 0 AS expected, SUM(l.amount) AS actual,
+			       format('journals for the reward are off by %s %s', SUM(l.amount), l.commodity) AS detail
+			FROM journal_lines l
+			JOIN journals j ON j.id = l.journal_id
+			JOIN rewards r ON r.id = j.reward_id
+			GROUP BY j.reward_id, r.user_id, l.commodity
+			HAVING SUM(l.amount) <> 0`,
+	},
+	{
+		name:     CheckRewardUnposted,
+		severity: SeverityCritical,
+		query: `
+			SELECT e.reward_id, r.user_id, e.stock_symbol, e.stock_units AS expected, 0 AS actual,
+			       format('%s ledger entry %s has no journal', e.entry_type, e.id) AS detail
+			FROM ledger_entries e
+			JOIN rewards r ON r.id = e.reward_id
+			WHERE NOT EXISTS (SELECT 1 FROM journals j WHERE j.ledger_entry_id = e.id)`,
+	},
+	{
+		// Bought rewards hold their full quantity, rewards still being
+		// bought hold what the broker allocated, anything else holds none
+		name:     CheckRewardUnits,
+		severity: SeverityCritical,
+		query: `
+			WITH ` + allocatedCTE + `,
+			ledger AS (
+				SELECT reward_id, SUM(stock_units) AS units
+				FROM ledger_entries
+				WHERE reward_id IS NOT NULL
+				GROUP BY reward_id
+			), expected AS (
+				SELECT r.id, r.user_id, r.stock_symbol, r.status,
+				       CASE WHEN r.status IN ($1, $2) THEN r.quantity
+				            WHEN r.status IN ($3, $4) THEN COALESCE(a.units, 0)
+				            ELSE 0 END AS expected,
+				       COALESCE(g.units, 0) AS actual
+				FROM rewards r
+				LEFT JOIN allocated a ON a.reward_id = r.id
+				LEFT JOIN ledger g ON g.reward_id = r.id
+			)
+			SELECT id AS reward_id, user_id, stock_symbol, expected, actual,
+			       format('%s reward has %s units in the ledger, expected %s', status, actual, expected) AS detail
+			FROM expected
+			WHERE expected <> actual`,
+		args: []interface{}{reward.StatusPurchased, reward.StatusSettled, reward.StatusPending, reward.StatusOrdered},
+	},
+	{
+		name:     CheckRewardJournalUnits,
+		severity: SeverityCritical,
+		query: `
+			WITH ledger AS (
+				SELECT reward_id, stock_symbol, SUM(stock_units) AS units
+				FROM ledger_entries
+				WHERE reward_id IS NOT NULL
+				GROUP BY reward_id, stock_symbol
+			), books AS (
+				SELECT j.reward_id, l.commodity AS stock_symbol, -SUM(l.amount) AS units
+				FROM journal_lines l
+				JOIN journals j ON j.id = l.journal_id
+				JOIN accounts a ON a.id = l.account_id
+				WHERE j.reward_id IS NOT NULL AND a.user_id IS NOT NULL
+				GROUP BY j.reward_id, l.commodity
+			)
+			SELECT r.id AS reward_id, r.user_id, COALESCE(g.stock_symbol, b.stock_symbol) AS stock_symbol,
+			       COALESCE(g.units, 0) AS expected, COALESCE(b.units, 0) AS actual,
+			       format('journals credit %s units to user holdings, ledger entries record %s',
+			              COALESCE(b.units, 0), COALESCE(g.units, 0)) AS detail
+			FROM ledger g
+			FULL JOIN books b ON b.reward_id = g.reward_id AND b.stock_symbol = g.stock_symbol
+			JOIN rewards r ON r.id = COALESCE(g.reward_id, b.reward_id)
+			WHERE COALESCE(g.units, 0) <> COALESCE(b.units, 0)`,
+	},
+	{
+		name:     CheckTaxUnpaid,
+		severity: SeverityWarning,
+		query: `
+			SELECT j.reward_id, r.user_id, r.stock_symbol, 0 AS expected, -SUM(l.amount) AS actual,
+			       format('settled reward still owes INR %s of STT and GST', -SUM(l.amount)) AS detail
+			FROM journal_lines l
+			JOIN journals j ON j.id = l.journal_id
+			JOIN accounts a ON a.id = l.account_id
+			JOIN rewards r ON r.id = j.reward_id
+			WHERE a.code = $1 AND l.commodity = $2 AND r.status = $3
+			GROUP BY j.reward_id, r.user_id, r.stock_symbol
+			HAVING SUM(l.amount) <> 0`,
+		args: []interface{}{accounting.AccountTaxPayable, accounting.CommodityINR, reward.StatusSettled},
+	},
+	{
+		// Users own every reward that has not failed or been reversed, but
+		// the ledger only holds the part the broker has bought so far
+		name:     CheckUserHoldings,
+		severity: SeverityCritical,
+		query: `
+			WITH ` + allocatedCTE + `,
+			granted AS (
+				SELECT r.user_id, r.stock_symbol, SUM(r.quantity) AS quantity,
+				       SUM(CASE WHEN r.status IN ($1, $2) THEN r.quantity - COALESCE(a.units, 0) ELSE 0 END) AS unfilled
+				FROM rewards r
+				LEFT JOIN allocated a ON a.reward_id = r.id
+				WHERE r.status NOT IN ($3, $4)
+				GROUP BY r.user_id, r.stock_symbol
+			), books AS (
+				SELECT a.user_id, l.commodity AS stock_symbol, -SUM(l.amount) AS units
+				FROM journal_lines l
+				JOIN accounts a ON a.id = l.account_id
+				WHERE a.user_id IS NOT NULL
+				GROUP BY a.user_id, l.commodity
+			)
+			SELECT COALESCE(g.user_id, b.user_id) AS user_id, COALESCE(g.stock_symbol, b.stock_symbol) AS stock_symbol,
+			       COALESCE(g.quantity, 0) AS expected, COALESCE(b.units, 0) AS actual,
+			       format('rewards total %s units with %s not yet bought, ledger holds %s',
+			              COALESCE(g.quantity, 0), COALESCE(g.unfilled, 0), COALESCE(b.units, 0)) AS detail
+			FROM granted g
+			FULL JOIN books b ON b.user_id = g.user_id AND b.stock_symbol = g.stock_symbol
+			WHERE COALESCE(g.quantity, 0) - COALESCE(g.unfilled, 0) <> COALESCE(b.units, 0)`,
+		args: []interface{}{reward.StatusPending, reward.StatusOrdered, reward.StatusFailed, reward.StatusReversed},
+	},
+	{
+		name:     CheckHoldingsTable,
+		severity: SeverityCritical,
+		query: `
+			WITH granted AS (
+				SELECT user_id, stock_symbol, SUM(quantity) AS quantity
+				FROM rewards
+				WHERE status NOT IN ($1, $2)
+				GROUP BY user_id, stock_symbol
+			)
+			SELECT COALESCE(g.user_id, h.user_id) AS user_id, COALESCE(g.stock_symbol, h.stock_symbol) AS stock_symbol,
+			       COALESCE(g.quantity, 0) AS expected, COALESCE(h.quantity, 0) AS actual,
+			       format('holdings table has %s units, rewards total %s',
+			              COALESCE(h.quantity, 0), COALESCE(g.quantity, 0)) AS detail
+			FROM granted g
+			FULL JOIN holdings h ON h.user_id = g.user_id AND h.stock_symbol = g.stock_symbol
+			WHERE COALESCE(g.quantity, 0) <> COALESCE(h.quantity, 0)`,
+		args: []interface{}{reward.StatusFailed, reward.StatusReversed},
+	},
+	{
+		// A shortfall means users were credited shares the company does
+		// not hold; a surplus is shares bought that nobody was credited
+		name: CheckInventoryShortfall,
+		query: `
+			WITH inventory AS (
+				SELECT l.commodity AS stock_symbol, SUM(l.amount) AS units
+				FROM journal_lines l
+				JOIN accounts a ON a.id = l.account_id
+				WHERE a.code = $1
+				GROUP BY l.commodity
+			), held AS (
+				SELECT l.commodity AS stock_symbol, -SUM(l.amount) AS units
+				FROM journal_lines l
+				JOIN accounts a ON a.id = l.account_id
+				WHERE a.user_id IS NOT NULL
+				GROUP BY l.commodity
+			)
+			SELECT COALESCE(i.stock_symbol, h.stock_symbol) AS stock_symbol,
+			       CASE WHEN COALESCE(i.units, 0) < COALESCE(h.units, 0) THEN $2 ELSE $3 END AS check_name,
+			       CASE WHEN COALESCE(i.units, 0) < COALESCE(h.units, 0) THEN $4 ELSE $5 END AS severity,
+			       COALESCE(h.units, 0) AS expected, COALESCE(i.units, 0) AS actual,
+			       format('inventory holds %s units against %s held for users',
+			              COALESCE(i.units, 0), COALESCE(h.units, 0)) AS detail
+			FROM inventory i
+			FULL JOIN held h ON h.stock_symbol = i.stock_symbol
+			WHERE COALESCE(i.units, 0) <> COALESCE(h.units, 0)`,
+		args: []interface{}{accounting.AccountStockInventory, CheckInventoryShortfall, CheckInventorySurplus,
+			SeverityCritical, SeverityWarning},
+	},
+}
+
+// runChecks runs every check against one snapshot of the database
+func runChecks(ctx context.Context, tx *sqlx.Tx) ([]Finding, error) {
+	findings := []Finding{}
+	for _, c := range checks {
+		rows := []Finding{}
+		if err := tx.SelectContext(ctx, &rows, c.query, c.args...); err != nil {
+			return findings, err
+		}
+		for _, f := range rows {
+			if f.Check == "" {
+				f.Check = c.name
+			}
+			if f.Severity == "" {
+				f.Severity = c.severity
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,74 @@
+package reconciliation
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type ReconciliationHandler struct {
+	service *ReconciliationService
+}
+
+func NewReconciliationHandler(service *ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{service: service}
+}
+
+// StartRun handles POST /admin/reconciliation/runs, running the checks now
+func (h *ReconciliationHandler) StartRun(c *gin.Context) {
+	run, err := h.service.Run(c.Request.Context(), TriggerManual)
+	if errors.Is(err, ErrRunInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Manual reconciliation failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reconciliation run failed", "run_id": run.ID})
+		return
+	}
+
+	c.JSON(http.StatusCreated, run)
+}
+
+// ListRuns handles GET /admin/reconciliation/runs?limit=20
+func (h *ReconciliationHandler) ListRuns(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	runs, err := h.service.ListRuns(c.Request.Context(), limit)
+	if err != nil {
+		logger.Log.Errorf("Failed to list reconciliation runs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list reconciliation runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// GetRun handles GET /admin/reconciliation/runs/:id
+func (h *ReconciliationHandler) GetRun(c *gin.Context) {
+	runID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run id"})
+		return
+	}
+
+	run, err := h.service.GetRun(c.Request.Context(), runID)
+	if errors.Is(err, ErrRunNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Failed to fetch reconciliation run %d: %v", runID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reconciliation run"})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
@@ -0,0 +1,63 @@
+package reconciliation
+
+import "time"
+
+// How a run was started
+const (
+	TriggerScheduled = "scheduled"
+	TriggerManual    = "manual"
+)
+
+// Run outcomes
+const (
+	RunClean         = "clean"
+	RunDiscrepancies = "discrepancies"
+	RunFailed        = "failed"
+)
+
+// Finding severities. Critical findings mean money or shares are
+// unaccounted for; warnings are books that are consistent but stale.
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+)
+
+// Checks
+const (
+	CheckRewardUnbalanced   = "reward_unbalanced"    // a reward's journals do not sum to zero in a commodity
+	CheckRewardUnposted     = "reward_unposted"      // a reward's ledger entry has no journal
+	CheckRewardUnits        = "reward_units"         // ledger units do not match what the reward's status implies
+	CheckRewardJournalUnits = "reward_journal_units" // the reward's journals moved different units than its ledger entries
+	CheckTaxUnpaid          = "tax_unpaid"           // a settled reward still owes STT or GST
+	CheckUserHoldings       = "user_holdings"        // ledger holdings differ from the user's rewards
+	CheckHoldingsTable      = "holdings_table"       // the holdings table differs from the user's rewards
+	CheckInventoryShortfall = "inventory_shortfall"  // inventory does not cover what users hold
+	CheckInventorySurplus   = "inventory_surplus"    // inventory holds shares no user owns
+)
+
+type Run struct {
+	ID         int       `db:"id" json:"id"`
+	Trigger    string    `db:"triggered_by" json:"trigger"`
+	Status     string    `db:"status" json:"status"`
+	StartedAt  time.Time `db:"started_at" json:"started_at"`
+	FinishedAt time.Time `db:"finished_at" json:"finished_at"`
+	Critical   int       `db:"critical" json:"critical"`
+	Warnings   int       `db:"warnings" json:"warnings"`
+	Error      string    `db:"error" json:"error,omitempty"`
+	Findings   []Finding `db:"-" json:"findings,omitempty"`
+}
+
+// Finding is one discrepancy. Expected and Actual are in the unit of the
+// check: share units, or INR for tax_unpaid.
+type Finding struct {
+	ID       int     `db:"id" json:"id"`
+	RunID    int     `db:"run_id" json:"run_id"`
+	Check    string  `db:"check_name" json:"check"`
+	Severity string  `db:"severity" json:"severity"`
+	RewardID *int    `db:"reward_id" json:"reward_id,omitempty"`
+	UserID   *int    `db:"user_id" json:"user_id,omitempty"`
+	Symbol   string  `db:"stock_symbol" json:"symbol,omitempty"`
+	Expected float64 `db:"expected" json:"expected"`
+	Actual   float64 `db:"actual" json:"actual"`
+	Detail   string  `db:"detail" json:"detail"`
+}
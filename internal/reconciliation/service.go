@@ -0,0 +1,215 @@
+package reconciliation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+	"github.com/angad363/stocky-assignment/internal/db"
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrRunNotFound   = errors.New("reconciliation run not found")
+	ErrRunInProgress = errors.New("a reconciliation run is already in progress")
+)
+
+const runColumns = `id, triggered_by, status, started_at, finished_at, critical, warnings, error`
+
+type ReconciliationService struct {
+	db *sqlx.DB
+}
+
+func NewReconciliationService(db *sqlx.DB) *ReconciliationService {
+	return &ReconciliationService{db: db}
+}
+
+// Run checks rewards, ledger entries, journals and inventory against each
+// other, stores what disagrees and logs every finding as an alert. The
+// checks share one repeatable-read snapshot, so rewards moving while the
+// run is in progress cannot show up as discrepancies.
+func (s *ReconciliationService) Run(ctx context.Context, trigger string) (Run, error) {
+	run := Run{Trigger: trigger, StartedAt: time.Now(), Findings: []Finding{}}
+
+	err := s.run(ctx, &run)
+	if errors.Is(err, ErrRunInProgress) {
+		return run, err
+	}
+	if err != nil {
+		// Record the failure so a broken nightly run is visible in the list
+		run.Status = RunFailed
+		run.FinishedAt = time.Now()
+		run.Error = err.Error()
+		if insertErr := s.db.GetContext(ctx, &run.ID, `
+			INSERT INTO reconciliation_runs (triggered_by, status, started_at, finished_at, error)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, run.Trigger, run.Status, run.StartedAt, run.FinishedAt, run.Error); insertErr != nil {
+			logger.Log.Errorf("Failed to record failed reconciliation run: %v", insertErr)
+		}
+		return run, err
+	}
+
+	alert(run)
+	return run, nil
+}
+
+func (s *ReconciliationService) run(ctx context.Context, run *Run) error {
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var leader bool
+	if err := tx.GetContext(ctx, &leader, `SELECT pg_try_advisory_xact_lock($1)`, db.LockReconciliation); err != nil {
+		return err
+	}
+	if !leader {
+		return ErrRunInProgress
+	}
+
+	if run.Findings, err = runChecks(ctx, tx); err != nil {
+		return err
+	}
+	for _, f := range run.Findings {
+		if f.Severity == SeverityCritical {
+			run.Critical++
+		} else {
+			run.Warnings++
+		}
+	}
+	run.Status = RunClean
+	if len(run.Findings) > 0 {
+		run.Status = RunDiscrepancies
+	}
+	run.FinishedAt = time.Now()
+
+	err = tx.GetContext(ctx, &run.ID, `
+		INSERT INTO reconciliation_runs (triggered_by, status, started_at, finished_at, critical, warnings)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, run.Trigger, run.Status, run.StartedAt, run.FinishedAt, run.Critical, run.Warnings)
+	if err != nil {
+		return err
+	}
+
+	for i := range run.Findings {
+		f := &run.Findings[i]
+		f.RunID = run.ID
+		err = tx.GetContext(ctx, &f.ID, `
+			INSERT INTO reconciliation_findings
+				(run_id, check_name, severity, reward_id, user_id, stock_symbol, expected, actual, detail)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id
+		`, f.RunID, f.Check, f.Severity, f.RewardID, f.UserID, f.Symbol, f.Expected, f.Actual, f.Detail)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// alert logs each finding at a level matching its severity
+func alert(run Run) {
+	for _, f := range run.Findings {
+		fields := logrus.Fields{
+			"run_id":   run.ID,
+			"check":    f.Check,
+			"severity": f.Severity,
+			"symbol":   f.Symbol,
+			"expected": f.Expected,
+			"actual":   f.Actual,
+		}
+		if f.RewardID != nil {
+			fields["reward_id"] = *f.RewardID
+		}
+		if f.UserID != nil {
+			fields["user_id"] = *f.UserID
+		}
+		entry := logger.Log.WithFields(fields)
+		if f.Severity == SeverityCritical {
+			entry.Error("🚨 Reconciliation: " + f.Detail)
+		} else {
+			entry.Warn("Reconciliation: " + f.Detail)
+		}
+	}
+
+	entry := logger.Log.WithFields(logrus.Fields{
+		"run_id":   run.ID,
+		"trigger":  run.Trigger,
+		"critical": run.Critical,
+		"warnings": run.Warnings,
+		"duration": run.FinishedAt.Sub(run.StartedAt).String(),
+	})
+	if run.Status == RunClean {
+		entry.Info("✅ Reconciliation run clean")
+	} else {
+		entry.Error("Reconciliation run found discrepancies")
+	}
+}
+
+// ListRuns returns the latest runs without their findings
+func (s *ReconciliationService) ListRuns(ctx context.Context, limit int) ([]Run, error) {
+	runs := []Run{}
+	err := s.db.SelectContext(ctx, &runs, `
+		SELECT `+runColumns+`
+		FROM reconciliation_runs
+		ORDER BY started_at DESC, id DESC
+		LIMIT $1
+	`, limit)
+	return runs, err
+}
+
+// GetRun returns a run with its findings, critical first
+func (s *ReconciliationService) GetRun(ctx context.Context, runID int) (Run, error) {
+	var run Run
+	err := s.db.GetContext(ctx, &run, `SELECT `+runColumns+` FROM reconciliation_runs WHERE id = $1`, runID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return run, ErrRunNotFound
+	}
+	if err != nil {
+		return run, err
+	}
+
+	run.Findings = []Finding{}
+	err = s.db.SelectContext(ctx, &run.Findings, `
+		SELECT id, run_id, check_name, severity, reward_id, user_id, stock_symbol, expected, actual, detail
+		FROM reconciliation_findings
+		WHERE run_id = $1
+		ORDER BY severity = $2 DESC, check_name, id
+	`, runID, SeverityCritical)
+	return run, err
+}
+
+// StartNightly runs the reconciliation every day at the hour and minute of
+// at, in IST
+func StartNightly(service *ReconciliationService, at time.Time) {
+	logger.Log.WithField("at", at.Format("15:04")+" IST").Info("🧮 Nightly reconciliation scheduled")
+
+	go func() {
+		for {
+			time.Sleep(time.Until(nextRun(time.Now(), at)))
+			_, err := service.Run(context.Background(), TriggerScheduled)
+			if errors.Is(err, ErrRunInProgress) {
+				logger.Log.Info("Nightly reconciliation skipped, another instance is running it")
+			} else if err != nil {
+				logger.Log.Errorf("Nightly reconciliation failed: %v", err)
+			}
+		}
+	}()
+}
+
+func nextRun(now, at time.Time) time.Time {
+	now = now.In(calendar.IST)
+	next := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, calendar.IST)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
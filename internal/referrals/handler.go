@@ -1,9 +1,12 @@
 package referral
 
 import (
-	"context"
+	"errors"
 	"net/http"
 
+	"github.com/angad363/stocky-assignment/internal/auth"
+	"github.com/angad363/stocky-assignment/internal/instruments"
+	"github.com/angad363/stocky-assignment/internal/reward"
 	"github.com/angad363/stocky-assignment/pkg/logger"
 	"github.com/gin-gonic/gin"
 )
@@ -29,8 +32,26 @@ func (h *ReferralHandler) CreateReferral(c *gin.Context) {
 		return
 	}
 
-	ref, reward, err := h.service.CreateReferral(context.Background(), req.UserID, req.FriendName)
-	if err != nil {
+	if principal, ok := auth.FromContext(c); !ok || principal.UserID != req.UserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "referrals can only be made for your own account"})
+		return
+	}
+
+	ref, rwd, err := h.service.CreateReferral(c.Request.Context(), req.UserID, req.FriendName)
+	switch {
+	case errors.Is(err, reward.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, reward.ErrUserInactive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, instruments.ErrNotFound), errors.Is(err, instruments.ErrInactive),
+		errors.Is(err, instruments.ErrNoActive), errors.Is(err, instruments.ErrQuantityPrecision),
+		errors.Is(err, instruments.ErrInvalidQuantity):
+		logger.Log.Warnf("Rejected referral reward for user %d: %v", req.UserID, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
 		logger.Log.Errorf("Failed to create referral for user %d: %v", req.UserID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create referral"})
 		return
@@ -44,6 +65,6 @@ func (h *ReferralHandler) CreateReferral(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{
 		"message":  "Referral successful! Reward granted.",
 		"referral": ref,
-		"reward":   reward,
+		"reward":   rwd,
 	})
 }
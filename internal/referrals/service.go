@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/angad363/stocky-assignment/internal/events"
 	"github.com/angad363/stocky-assignment/internal/reward"
 	"github.com/jmoiron/sqlx"
 )
@@ -21,7 +23,26 @@ func (s *ReferralService) CreateReferral(ctx context.Context, referrerID int, fr
 	var ref Referral
 	var rwd reward.Reward
 
-	err := s.db.QueryRowContext(ctx, `
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return ref, rwd, err
+	}
+	defer tx.Rollback()
+
+	// Give a random stock reward for successful referral. It is written
+	// first so an unknown or deactivated referrer is reported as such.
+	rwdReq := reward.RewardRequest{
+		UserID:   referrerID,
+		Quantity: 1.0,
+		Symbol:   "",
+		Campaign: reward.CampaignReferral,
+	}
+	rwd, err = s.rewardSvc.CreateRewardTx(ctx, tx, rwdReq)
+	if err != nil {
+		return ref, rwd, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
 		INSERT INTO referrals (referrer_id, friend_name, created_at)
 		VALUES ($1, $2, $3)
 		RETURNING id, referrer_id, friend_name, created_at
@@ -32,16 +53,13 @@ func (s *ReferralService) CreateReferral(ctx context.Context, referrerID int, fr
 		return ref, rwd, err
 	}
 
-	// Give a random stock reward for successful referral
-	rwdReq := reward.RewardRequest{
-		UserID:   ref.ReferrerID,
-		Quantity: 1.0,
-		Symbol:   "",
-	}
-	rwd, err = s.rewardSvc.CreateReward(ctx, rwdReq)
+	err = events.Write(ctx, tx.Tx, events.ReferralConverted, ref.ReferrerID, map[string]interface{}{
+		"referral": ref,
+		"reward":   rwd,
+	})
 	if err != nil {
 		return ref, rwd, err
 	}
 
-	return ref, rwd, nil
+	return ref, rwd, tx.Commit()
 }
\ No newline at end of file
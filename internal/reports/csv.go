@@ -0,0 +1,33 @@
+package reports
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+var expenseHeader = []string{"key", "cash_outflow", "brokerage_fee", "stt", "gst", "other_fees",
+	"total_fees", "total_cost", "rewards", "users", "avg_cost_per_reward", "avg_cost_per_user"}
+
+// WriteExpensesCSV writes one line per group followed by the total
+func WriteExpensesCSV(w io.Writer, report ExpenseReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(expenseHeader); err != nil {
+		return err
+	}
+	for _, row := range append(report.Rows, report.Total) {
+		writer.Write([]string{
+			row.Key,
+			formatINR(row.CashOutflow), formatINR(row.Brokerage), formatINR(row.STT), formatINR(row.GST),
+			formatINR(row.OtherFees), formatINR(row.TotalFees), formatINR(row.TotalCost),
+			strconv.Itoa(row.Rewards), strconv.Itoa(row.Users),
+			formatINR(row.AvgCostPerReward), formatINR(row.AvgCostPerUser),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatINR(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
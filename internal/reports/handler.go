@@ -0,0 +1,80 @@
+package reports
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type ReportHandler struct {
+	service *ReportService
+}
+
+func NewReportHandler(service *ReportService) *ReportHandler {
+	return &ReportHandler{service: service}
+}
+
+// GetExpenses handles GET /admin/reports/expenses?from=YYYY-MM-DD&to=YYYY-MM-DD
+// &group_by=day|month|symbol|campaign&format=json|csv. The period defaults
+// to the current month to date, grouped by day.
+func (h *ReportHandler) GetExpenses(c *gin.Context) {
+	from, to, ok := period(c)
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	report, err := h.service.Expenses(c.Request.Context(), from, to, c.DefaultQuery("group_by", GroupByDay))
+	switch {
+	case errors.Is(err, ErrInvalidGroupBy), errors.Is(err, ErrInvalidPeriod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Log.Errorf("Failed to build expense report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build expense report"})
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := WriteExpensesCSV(&buf, report); err != nil {
+		logger.Log.Errorf("Failed to write expense report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build expense report"})
+		return
+	}
+	filename := fmt.Sprintf("expenses-%s-%s-by-%s.csv", report.From, report.To, report.GroupBy)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "text/csv", buf.Bytes())
+}
+
+// period reads the from and to IST dates, defaulting to month to date
+func period(c *gin.Context) (time.Time, time.Time, bool) {
+	today := time.Now().In(calendar.IST)
+	from := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, calendar.IST)
+	to := today
+	for param, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		if v := c.Query(param); v != "" {
+			day, err := time.ParseInLocation("2006-01-02", v, calendar.IST)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + ", use YYYY-MM-DD"})
+				return from, to, false
+			}
+			*dst = day
+		}
+	}
+	return from, to, true
+}
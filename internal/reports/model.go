@@ -0,0 +1,37 @@
+package reports
+
+// Expense report groupings
+const (
+	GroupByDay      = "day"
+	GroupByMonth    = "month"
+	GroupBySymbol   = "symbol"
+	GroupByCampaign = "campaign"
+)
+
+// ExpenseRow aggregates ledger postings for one group. Reversals are
+// netted in, so amounts are what the company actually spent.
+type ExpenseRow struct {
+	Key         string  `db:"key" json:"key"`
+	CashOutflow float64 `db:"cash_outflow" json:"cash_outflow"` // paid for the shares themselves
+	Brokerage   float64 `db:"brokerage_fee" json:"brokerage_fee"`
+	STT         float64 `db:"stt" json:"stt"`
+	GST         float64 `db:"gst" json:"gst"`
+	OtherFees   float64 `db:"other_fees" json:"other_fees"`
+	TotalFees   float64 `db:"-" json:"total_fees"`
+	TotalCost   float64 `db:"-" json:"total_cost"`
+	Rewards     int     `db:"rewards" json:"rewards"`
+	Users       int     `db:"users" json:"users"`
+
+	AvgCostPerReward float64 `db:"-" json:"avg_cost_per_reward"`
+	AvgCostPerUser   float64 `db:"-" json:"avg_cost_per_user"`
+
+	TotalRow bool `db:"total_row" json:"-"`
+}
+
+type ExpenseReport struct {
+	From    string       `json:"from"` // IST dates, both inclusive
+	To      string       `json:"to"`
+	GroupBy string       `json:"group_by"`
+	Rows    []ExpenseRow `json:"rows"`
+	Total   ExpenseRow   `json:"total"`
+}
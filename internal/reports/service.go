@@ -0,0 +1,106 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrInvalidGroupBy = errors.New("group_by must be day, month, symbol or campaign")
+	ErrInvalidPeriod  = errors.New("from must not be after to")
+)
+
+// groupKeys are trusted SQL fragments, selected by the validated group_by
+var groupKeys = map[string]string{
+	GroupByDay:      `to_char(l.created_at AT TIME ZONE 'Asia/Kolkata', 'YYYY-MM-DD')`,
+	GroupByMonth:    `to_char(l.created_at AT TIME ZONE 'Asia/Kolkata', 'YYYY-MM')`,
+	GroupBySymbol:   `l.stock_symbol`,
+	GroupByCampaign: `COALESCE(r.campaign, 'unallocated')`,
+}
+
+type ReportService struct {
+	db *sqlx.DB
+}
+
+func NewReportService(db *sqlx.DB) *ReportService {
+	return &ReportService{db: db}
+}
+
+// Expenses aggregates ledger postings dated in the IST days from..to
+// inclusive. Fee adjustments from contract notes have no reward, so they
+// count towards fees but not towards rewards or users.
+func (s *ReportService) Expenses(ctx context.Context, from, to time.Time, groupBy string) (ExpenseReport, error) {
+	report := ExpenseReport{GroupBy: groupBy, Rows: []ExpenseRow{}}
+
+	key, ok := groupKeys[groupBy]
+	if !ok {
+		return report, ErrInvalidGroupBy
+	}
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, calendar.IST)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, calendar.IST)
+	if from.After(to) {
+		return report, ErrInvalidPeriod
+	}
+	report.From = from.Format("2006-01-02")
+	report.To = to.Format("2006-01-02")
+
+	// The empty grouping set adds the grand total, so distinct reward and
+	// user counts are not double counted across groups
+	rows := []ExpenseRow{}
+	err := s.db.SelectContext(ctx, &rows, `
+		WITH postings AS (
+			SELECT `+key+` AS key, l.*, r.user_id AS acquired_user
+			FROM ledger_entries l
+			LEFT JOIN rewards r ON r.id = l.reward_id
+			WHERE l.created_at >= $1 AND l.created_at < $2
+		)
+		SELECT GROUPING(key) = 1 AS total_row, COALESCE(key, '') AS key,
+		       COALESCE(SUM(cash_outflow), 0) AS cash_outflow,
+		       COALESCE(SUM(brokerage_fee), 0) AS brokerage_fee,
+		       COALESCE(SUM(stt), 0) AS stt,
+		       COALESCE(SUM(gst), 0) AS gst,
+		       COALESCE(SUM(other_fees), 0) AS other_fees,
+		       COUNT(DISTINCT reward_id) AS rewards,
+		       COUNT(DISTINCT acquired_user) AS users
+		FROM postings
+		GROUP BY GROUPING SETS ((key), ())
+		ORDER BY total_row, key
+	`, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return report, err
+	}
+
+	for _, row := range rows {
+		row.CashOutflow = round2(row.CashOutflow)
+		row.Brokerage = round2(row.Brokerage)
+		row.STT = round2(row.STT)
+		row.GST = round2(row.GST)
+		row.OtherFees = round2(row.OtherFees)
+		row.TotalFees = round2(row.Brokerage + row.STT + row.GST + row.OtherFees)
+		row.TotalCost = round2(row.CashOutflow + row.TotalFees)
+		if row.Rewards > 0 {
+			row.AvgCostPerReward = round2(row.TotalCost / float64(row.Rewards))
+		}
+		if row.Users > 0 {
+			row.AvgCostPerUser = round2(row.TotalCost / float64(row.Users))
+		}
+
+		if row.TotalRow {
+			row.Key = "total"
+			report.Total = row
+		} else {
+			report.Rows = append(report.Rows, row)
+		}
+	}
+
+	return report, nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,283 @@
+package reward
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/angad363/stocky-assignment/internal/auth"
+	"github.com/angad363/stocky-assignment/internal/instruments"
+	"github.com/angad363/stocky-assignment/internal/price"
+	"github.com/jmoiron/sqlx"
+)
+
+// Maker-checker: manual rewards worth more than the threshold are held as
+// approval requests and only written once a second admin approves them.
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+
+	decisionRequested = "requested"
+)
+
+var (
+	ErrApprovalNotFound = errors.New("approval request not found")
+	ErrApprovalDecided  = errors.New("approval request has already been decided")
+	ErrSelfApproval     = errors.New("approval requests must be decided by someone other than the requester")
+	ErrCommentRequired  = errors.New("a comment is required when rejecting")
+)
+
+type ApprovalService struct {
+	db            *sqlx.DB
+	rewardSvc     *RewardService
+	priceSvc      *price.PriceService
+	instrumentSvc *instruments.InstrumentService
+	thresholdINR  float64
+}
+
+func NewApprovalService(db *sqlx.DB, rewardSvc *RewardService, priceSvc *price.PriceService, instrumentSvc *instruments.InstrumentService, thresholdINR float64) *ApprovalService {
+	return &ApprovalService{
+		db:            db,
+		rewardSvc:     rewardSvc,
+		priceSvc:      priceSvc,
+		instrumentSvc: instrumentSvc,
+		thresholdINR:  thresholdINR,
+	}
+}
+
+const approvalColumns = `id, user_id, stock_symbol, quantity, campaign, price_at_request, value_inr,
+	status, requested_by_user_id, requested_by_api_key_id, requested_at, decided_at, reward_id`
+
+// RequireApproval values a reward request made through the API, whatever
+// its campaign. Above the threshold it stores an approval request and
+// returns it; otherwise it returns nil and the caller creates the reward
+// directly. A random symbol is pinned on req so the reward issued is the
+// one that was valued.
+func (s *ApprovalService) RequireApproval(ctx context.Context, req *RewardRequest, requester auth.Principal) (*RewardApproval, error) {
+	if s.thresholdINR <= 0 {
+		return nil, nil
+	}
+	if _, err := checkRecipient(ctx, s.db, req.UserID); err != nil {
+		return nil, err
+	}
+
+	if req.Symbol == "" {
+		inst, err := s.instrumentSvc.RandomActive(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.Symbol = inst.Symbol
+	}
+	inst, err := s.instrumentSvc.ValidateReward(ctx, req.Symbol, req.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	req.Symbol = inst.Symbol
+
+	quote, err := s.priceSvc.GetStockPrice(inst.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	value := RoundINR(quote.Price * req.Quantity)
+	if value <= s.thresholdINR {
+		return nil, nil
+	}
+
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := claimIdempotencyKey(ctx, tx.Tx, req.IdempotencyKey); err != nil {
+		return nil, err
+	}
+
+	var approval RewardApproval
+	err = tx.GetContext(ctx, &approval, `
+		INSERT INTO reward_approvals (user_id, stock_symbol, quantity, campaign, price_at_request,
+		                              value_inr, status, requested_by_user_id, requested_by_api_key_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+approvalColumns,
+		req.UserID, inst.Symbol, req.Quantity, req.Campaign, quote.Price,
+		value, ApprovalPending, nullableID(requester.UserID), nullableID(requester.APIKeyID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordDecision(ctx, tx.Tx, approval.ID, requester, decisionRequested,
+		fmt.Sprintf("reward worth ₹%.2f exceeds the ₹%.2f approval threshold", value, s.thresholdINR))
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{
+		Action:     audit.ActionApprovalRequested,
+		EntityType: "reward_approval",
+		EntityID:   strconv.Itoa(approval.ID),
+		After:      approval,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &approval, tx.Commit()
+}
+
+// Approve writes the held reward and its ledger/outbox rows in the same
+// transaction as the decision
+func (s *ApprovalService) Approve(ctx context.Context, id int, approver auth.Principal, comment string) (RewardApproval, error) {
+	tx, approval, err := s.lockPending(ctx, id, approver)
+	if err != nil {
+		return approval, err
+	}
+	defer tx.Rollback()
+
+	reward, err := s.rewardSvc.CreateRewardTx(ctx, tx, RewardRequest{
+		UserID:   approval.UserID,
+		Symbol:   approval.StockSymbol,
+		Quantity: approval.Quantity,
+		Campaign: approval.Campaign,
+	})
+	if err != nil {
+		return approval, err
+	}
+
+	if err := s.decide(ctx, tx, &approval, approver, ApprovalApproved, comment, &reward.ID); err != nil {
+		return approval, err
+	}
+	return approval, tx.Commit()
+}
+
+// Reject closes the request without writing a reward
+func (s *ApprovalService) Reject(ctx context.Context, id int, approver auth.Principal, comment string) (RewardApproval, error) {
+	if comment == "" {
+		return RewardApproval{}, ErrCommentRequired
+	}
+
+	tx, approval, err := s.lockPending(ctx, id, approver)
+	if err != nil {
+		return approval, err
+	}
+	defer tx.Rollback()
+
+	if err := s.decide(ctx, tx, &approval, approver, ApprovalRejected, comment, nil); err != nil {
+		return approval, err
+	}
+	return approval, tx.Commit()
+}
+
+// lockPending opens a transaction holding the approval row, checking that
+// it is still pending and that the approver is not the requester
+func (s *ApprovalService) lockPending(ctx context.Context, id int, approver auth.Principal) (*audit.Tx, RewardApproval, error) {
+	var approval RewardApproval
+
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return nil, approval, err
+	}
+
+	err = tx.GetContext(ctx, &approval, `
+		SELECT `+approvalColumns+` FROM reward_approvals WHERE id = $1 FOR UPDATE
+	`, id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = ErrApprovalNotFound
+	case err != nil:
+	case approval.Status != ApprovalPending:
+		err = ErrApprovalDecided
+	case approver.UserID == 0,
+		approval.RequestedByUserID != nil && *approval.RequestedByUserID == approver.UserID:
+		err = ErrSelfApproval
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, approval, err
+	}
+	return tx, approval, nil
+}
+
+func (s *ApprovalService) decide(ctx context.Context, tx *audit.Tx, approval *RewardApproval, approver auth.Principal, status, comment string, rewardID *int) error {
+	before := *approval
+	err := tx.GetContext(ctx, approval, `
+		UPDATE reward_approvals
+		SET status = $2, decided_at = NOW(), reward_id = $3
+		WHERE id = $1
+		RETURNING `+approvalColumns,
+		approval.ID, status, rewardID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := recordDecision(ctx, tx.Tx, approval.ID, approver, status, comment); err != nil {
+		return err
+	}
+
+	action := audit.ActionApprovalApproved
+	if status == ApprovalRejected {
+		action = audit.ActionApprovalRejected
+	}
+	return audit.Record(ctx, tx, audit.Entry{
+		Action:     action,
+		EntityType: "reward_approval",
+		EntityID:   strconv.Itoa(approval.ID),
+		Before:     before,
+		After:      map[string]interface{}{"approval": approval, "comment": comment},
+	})
+}
+
+func recordDecision(ctx context.Context, tx *sqlx.Tx, approvalID int, actor auth.Principal, action, comment string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO reward_approval_decisions (approval_id, actor_user_id, actor_api_key_id, action, comment)
+		VALUES ($1, $2, $3, $4, $5)
+	`, approvalID, nullableID(actor.UserID), nullableID(actor.APIKeyID), action, comment)
+	return err
+}
+
+// ListApprovals returns the newest approval requests, optionally by status
+func (s *ApprovalService) ListApprovals(ctx context.Context, status string, limit int) ([]RewardApproval, error) {
+	approvals := []RewardApproval{}
+	err := s.db.SelectContext(ctx, &approvals, `
+		SELECT `+approvalColumns+`
+		FROM reward_approvals
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY id DESC
+		LIMIT $2
+	`, status, limit)
+	return approvals, err
+}
+
+// GetApproval returns an approval request with its decision trail
+func (s *ApprovalService) GetApproval(ctx context.Context, id int) (RewardApproval, error) {
+	var approval RewardApproval
+	err := s.db.GetContext(ctx, &approval, `
+		SELECT `+approvalColumns+` FROM reward_approvals WHERE id = $1
+	`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return approval, ErrApprovalNotFound
+	}
+	if err != nil {
+		return approval, err
+	}
+
+	approval.Decisions = []ApprovalDecision{}
+	err = s.db.SelectContext(ctx, &approval.Decisions, `
+		SELECT id, approval_id, actor_user_id, actor_api_key_id, action, comment, created_at
+		FROM reward_approval_decisions
+		WHERE approval_id = $1
+		ORDER BY id
+	`, id)
+	return approval, err
+}
+
+func nullableID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,35 @@
+package reward
+
+import "math"
+
+// Estimated delivery-trade charges. Actual amounts come from the broker.
+const (
+	brokerageRate = 0.0003 // 0.03% of turnover
+	brokerageCap  = 20.0   // flat cap per order in INR
+	sttRate       = 0.001  // 0.1% on delivery buys
+	gstRate       = 0.18   // 18% on brokerage
+)
+
+// Fees is the company-side cost of buying shares for a reward
+type Fees struct {
+	CashOutflow float64 `json:"cash_outflow"`
+	Brokerage   float64 `json:"brokerage_fee"`
+	STT         float64 `json:"stt"`
+	GST         float64 `json:"gst"`
+}
+
+// EstimateFees computes charges for a buy of the given INR turnover
+func EstimateFees(turnover float64) Fees {
+	brokerage := math.Min(turnover*brokerageRate, brokerageCap)
+	return Fees{
+		CashOutflow: RoundINR(turnover),
+		Brokerage:   RoundINR(brokerage),
+		STT:         RoundINR(turnover * sttRate),
+		GST:         RoundINR(brokerage * gstRate),
+	}
+}
+
+// RoundINR rounds to the NUMERIC(18,4) precision used for INR columns
+func RoundINR(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -2,9 +2,15 @@ package reward
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/angad363/stocky-assignment/internal/auth"
+	"github.com/angad363/stocky-assignment/internal/calendar"
+	"github.com/angad363/stocky-assignment/internal/instruments"
 	"github.com/angad363/stocky-assignment/pkg/logger"
 	"github.com/gin-gonic/gin"
 )
@@ -12,10 +18,11 @@ import (
 type RewardHandler struct {
 	service     *RewardService
 	idemService *IdempotencyService
+	approvals   *ApprovalService
 }
 
-func NewRewardHandler(service *RewardService, idem *IdempotencyService) *RewardHandler {
-	return &RewardHandler{service: service, idemService: idem}
+func NewRewardHandler(service *RewardService, idem *IdempotencyService, approvals *ApprovalService) *RewardHandler {
+	return &RewardHandler{service: service, idemService: idem, approvals: approvals}
 }
 
 func (h *RewardHandler) CreateReward(c *gin.Context) {
@@ -25,6 +32,22 @@ func (h *RewardHandler) CreateReward(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
+	if req.Campaign == "" {
+		req.Campaign = CampaignManual
+	}
+	if !ValidCampaign(req.Campaign) {
+		logger.Log.Warnf("Rejected reward for unknown campaign %q", req.Campaign)
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%v %q", ErrUnknownCampaign, req.Campaign)})
+		return
+	}
+
+	// API keys may only issue within their allowed campaigns and quantity
+	principal, _ := auth.FromContext(c)
+	if err := principal.AllowsIssuance(req.Campaign, req.Quantity); err != nil {
+		logger.Log.Warnf("Reward issuance denied for API key %d: %v", principal.APIKeyID, err)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 
 	idemKey := c.GetHeader("Idempotency-Key")
 	if idemKey == "" {
@@ -33,22 +56,70 @@ func (h *RewardHandler) CreateReward(c *gin.Context) {
 		return
 	}
 
-	ctx := context.Background()
-	exists, _ := h.idemService.CheckOrSet(ctx, idemKey, nil)
-	if exists {
-		logger.Log.Warnf("Duplicate reward request detected for key: %s", idemKey)
-		c.JSON(http.StatusConflict, gin.H{"error": "Duplicate reward request detected"})
+	req.IdempotencyKey = idemKey
+
+	// The key is only stored with the reward or approval request it
+	// creates; this check just answers repeats without redoing the work
+	ctx := c.Request.Context()
+	seen, err := h.idemService.Seen(ctx, idemKey)
+	if err != nil {
+		logger.Log.Errorf("Failed to check Idempotency-Key %s: %v", idemKey, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create reward"})
+		return
+	}
+	if seen {
+		writeDuplicateError(c, idemKey)
+		return
+	}
+
+	// Large rewards wait for a second admin instead of posting now
+	approval, err := h.approvals.RequireApproval(ctx, &req, principal)
+	if writeRecipientError(c, err) {
+		return
+	}
+	if errors.Is(err, ErrDuplicateRequest) {
+		writeDuplicateError(c, idemKey)
+		return
+	}
+	if isInstrumentError(err) {
+		logger.Log.Warnf("Rejected reward for symbol %q: %v", req.Symbol, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Failed to screen reward for approval: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create reward"})
+		return
+	}
+	if approval != nil {
+		logger.Log.WithFields(map[string]interface{}{
+			"approval_id": approval.ID,
+			"user_id":     req.UserID,
+			"value_inr":   approval.ValueINR,
+		}).Info("Reward held for approval")
+		c.JSON(http.StatusAccepted, approval)
 		return
 	}
 
 	reward, err := h.service.CreateReward(ctx, req)
+	if writeRecipientError(c, err) {
+		return
+	}
+	if errors.Is(err, ErrDuplicateRequest) {
+		writeDuplicateError(c, idemKey)
+		return
+	}
+	if isInstrumentError(err) {
+		logger.Log.Warnf("Rejected reward for symbol %q: %v", req.Symbol, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logger.Log.Errorf("Failed to create reward: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create reward"})
 		return
 	}
 
-	_, _ = h.idemService.CheckOrSet(ctx, idemKey, reward)
 	logger.Log.WithFields(map[string]interface{}{
 		"user_id": req.UserID,
 	}).Info("Reward successfully created")
@@ -56,6 +127,72 @@ func (h *RewardHandler) CreateReward(c *gin.Context) {
 	c.JSON(http.StatusCreated, reward)
 }
 
+// TransitionReward handles POST /admin/rewards/:id/status
+func (h *RewardHandler) TransitionReward(c *gin.Context) {
+	rewardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reward id"})
+		return
+	}
+
+	var req TransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Log.Warnf("Invalid reward transition request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	reward, err := h.service.TransitionReward(c.Request.Context(), rewardID, req)
+	switch {
+	case errors.Is(err, ErrRewardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrInvalidTransition), errors.Is(err, ErrPriceRequired),
+		errors.Is(err, ErrOrderInFlight), errors.Is(err, ErrPartlyFilled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Log.Errorf("Failed to transition reward %d: %v", rewardID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update reward status"})
+		return
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"reward_id": rewardID,
+		"status":    reward.Status,
+	}).Info("Reward status updated")
+	c.JSON(http.StatusOK, reward)
+}
+
+// GetRewardHistory handles GET /rewards/:id/history
+func (h *RewardHandler) GetRewardHistory(c *gin.Context) {
+	rewardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reward id"})
+		return
+	}
+
+	history, err := h.service.GetRewardHistory(context.Background(), rewardID)
+	if errors.Is(err, ErrRewardNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Failed to fetch history for reward %d: %v", rewardID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reward history"})
+		return
+	}
+
+	// Report someone else's reward as missing rather than revealing it exists
+	principal, ok := auth.FromContext(c)
+	if !ok || (principal.UserID != history.Reward.UserID && !principal.HasRole(auth.RoleSupport, auth.RoleAdmin)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrRewardNotFound.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
 func (h *RewardHandler) GetTodayRewards(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("userId"))
 	if err != nil {
@@ -65,6 +202,10 @@ func (h *RewardHandler) GetTodayRewards(c *gin.Context) {
 	}
 
 	rewards, err := h.service.GetTodayRewards(context.Background(), userID)
+	if errors.Is(err, ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logger.Log.Errorf("Failed to fetch today's rewards for user %d: %v", userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch rewards"})
@@ -91,6 +232,10 @@ func (h *RewardHandler) GetHistoricalINR(c *gin.Context) {
 	}
 
 	data, err := h.service.GetHistoricalINR(context.Background(), userID)
+	if errors.Is(err, ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logger.Log.Errorf("Failed to fetch historical INR for user %d: %v", userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch historical INR"})
@@ -114,6 +259,10 @@ func (h *RewardHandler) GetUserStats(c *gin.Context) {
 
 	ctx := context.Background()
 	todaySummary, totalValue, err := h.service.GetUserStats(ctx, userID)
+	if errors.Is(err, ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logger.Log.Errorf("Failed to fetch stats for user %d: %v", userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch stats"})
@@ -141,6 +290,10 @@ func (h *RewardHandler) GetUserPortfolio(c *gin.Context) {
 	}
 
 	portfolio, err := h.service.GetUserPortfolio(context.Background(), userID)
+	if errors.Is(err, ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logger.Log.Errorf("Failed to fetch portfolio for user %d: %v", userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch portfolio"})
@@ -153,3 +306,222 @@ func (h *RewardHandler) GetUserPortfolio(c *gin.Context) {
 		"portfolio": portfolio,
 	})
 }
+
+// writeRecipientError answers for rewards addressed to unknown or
+// deactivated users
+func writeRecipientError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUserInactive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
+
+func writeDuplicateError(c *gin.Context, idemKey string) {
+	logger.Log.Warnf("Duplicate reward request detected for key: %s", idemKey)
+	c.JSON(http.StatusConflict, gin.H{"error": "Duplicate reward request detected"})
+}
+
+func isInstrumentError(err error) bool {
+	return errors.Is(err, instruments.ErrNotFound) ||
+		errors.Is(err, instruments.ErrInactive) ||
+		errors.Is(err, instruments.ErrNoActive) ||
+		errors.Is(err, instruments.ErrQuantityPrecision) ||
+		errors.Is(err, instruments.ErrInvalidQuantity)
+}
+
+// ListApprovals handles GET /admin/reward-approvals?status=pending&limit=50
+func (h *RewardHandler) ListApprovals(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", ApprovalPending, ApprovalApproved, ApprovalRejected:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	approvals, err := h.approvals.ListApprovals(context.Background(), status, limit)
+	if err != nil {
+		logger.Log.Errorf("Failed to list reward approvals: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list approvals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"approvals": approvals})
+}
+
+// GetApproval handles GET /admin/reward-approvals/:id
+func (h *RewardHandler) GetApproval(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid approval id"})
+		return
+	}
+
+	approval, err := h.approvals.GetApproval(context.Background(), id)
+	if errors.Is(err, ErrApprovalNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("Failed to fetch reward approval %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch approval"})
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+// ApproveReward handles POST /admin/reward-approvals/:id/approve
+func (h *RewardHandler) ApproveReward(c *gin.Context) {
+	h.decideApproval(c, h.approvals.Approve)
+}
+
+// RejectReward handles POST /admin/reward-approvals/:id/reject
+func (h *RewardHandler) RejectReward(c *gin.Context) {
+	h.decideApproval(c, h.approvals.Reject)
+}
+
+type decideFunc func(ctx context.Context, id int, approver auth.Principal, comment string) (RewardApproval, error)
+
+func (h *RewardHandler) decideApproval(c *gin.Context, decide decideFunc) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid approval id"})
+		return
+	}
+
+	var req DecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	principal, _ := auth.FromContext(c)
+	approval, err := decide(c.Request.Context(), id, principal, req.Comment)
+	switch {
+	case errors.Is(err, ErrApprovalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrApprovalDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrSelfApproval):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrUserInactive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrCommentRequired), errors.Is(err, ErrUnknownCampaign), isInstrumentError(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Log.Errorf("Failed to decide reward approval %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decide approval"})
+		return
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"approval_id": approval.ID,
+		"status":      approval.Status,
+		"decided_by":  principal.UserID,
+	}).Info("Reward approval decided")
+	c.JSON(http.StatusOK, approval)
+}
+
+// ListRewards handles GET /rewards?user_id=&symbol=&campaign=&status=
+// &from=YYYY-MM-DD&to=YYYY-MM-DD&sort=-rewarded_at&limit=20&cursor=
+// Users only see their own rewards; staff may list anyone's.
+func (h *RewardHandler) ListRewards(c *gin.Context) {
+	f := RewardFilter{
+		Symbol:   c.Query("symbol"),
+		Campaign: c.Query("campaign"),
+		Status:   c.Query("status"),
+	}
+
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		f.UserID = &id
+	}
+	principal, _ := auth.FromContext(c)
+	if !principal.HasRole(auth.RoleAdmin, auth.RoleSupport, auth.RoleFinance) {
+		if principal.UserID == 0 || (f.UserID != nil && *f.UserID != principal.UserID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "access to another user's data is not allowed"})
+			return
+		}
+		f.UserID = &principal.UserID
+	}
+
+	if _, ok := transitions[f.Status]; f.Status != "" && !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+
+	// Dates are IST calendar days; both ends are inclusive
+	for param, dst := range map[string]**time.Time{"from": &f.From, "to": &f.To} {
+		if v := c.Query(param); v != "" {
+			day, err := time.ParseInLocation("2006-01-02", v, calendar.IST)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + ", use YYYY-MM-DD"})
+				return
+			}
+			if param == "to" {
+				day = day.AddDate(0, 0, 1)
+			}
+			*dst = &day
+		}
+	}
+
+	switch c.DefaultQuery("sort", "-rewarded_at") {
+	case "-rewarded_at":
+		f.Desc = true
+	case "rewarded_at":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be rewarded_at or -rewarded_at"})
+		return
+	}
+
+	var err error
+	f.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || f.Limit <= 0 || f.Limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := DecodeCursor(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		f.Cursor = &cursor
+	}
+
+	page, err := h.service.ListRewards(c.Request.Context(), f)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cursor does not match the requested sort"})
+		return
+	case err != nil:
+		logger.Log.Errorf("Failed to list rewards: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list rewards"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
@@ -2,32 +2,62 @@ package reward
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"time"
 
-	"github.com/redis/go-redis/v9"
+	"github.com/jmoiron/sqlx"
 )
 
+// idempotencyTTL is how long an Idempotency-Key blocks repeats of its request
 const idempotencyTTL = time.Hour
 
+var ErrDuplicateRequest = errors.New("duplicate reward request detected")
+
+// IdempotencyService tracks the Idempotency-Keys of POST /reward. A key is
+// only stored by the transaction that creates the reward or approval
+// request it came with, so a request that fails leaves the key free for
+// the client's corrected retry.
 type IdempotencyService struct {
-	cache *redis.Client
+	db *sqlx.DB
 }
 
-func NewIdempotencyService(client *redis.Client) *IdempotencyService {
-	return &IdempotencyService{cache: client}
+func NewIdempotencyService(db *sqlx.DB) *IdempotencyService {
+	return &IdempotencyService{db: db}
 }
 
-func (s *IdempotencyService) CheckOrSet(ctx context.Context, key string, response any) (bool, error) {
-	val, err := s.cache.Get(ctx, key).Result()
-	if err == nil && val != "" {
-		return true, nil
-	}
+// Seen reports whether a request with the key has already succeeded. It
+// lets duplicates fail fast; claimIdempotencyKey is what enforces it.
+func (s *IdempotencyService) Seen(ctx context.Context, key string) (bool, error) {
+	var seen bool
+	err := s.db.GetContext(ctx, &seen, `
+		SELECT EXISTS (
+			SELECT 1 FROM reward_idempotency_keys
+			WHERE key = $1 AND created_at > NOW() - make_interval(secs => $2)
+		)
+	`, key, idempotencyTTL.Seconds())
+	return seen, err
+}
 
-	if response != nil {
-		jsonResp, _ := json.Marshal(response)
-		_ = s.cache.Set(ctx, key, jsonResp, idempotencyTTL).Err()
+// claimIdempotencyKey stores key in tx, or reuses it once it has expired.
+// A concurrent request with the same key waits for this transaction and
+// gets ErrDuplicateRequest if it commits.
+func claimIdempotencyKey(ctx context.Context, tx *sqlx.Tx, key string) error {
+	if key == "" {
+		return nil
 	}
-
-	return false, nil
-}
\ No newline at end of file
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO reward_idempotency_keys (key) VALUES ($1)
+		ON CONFLICT (key) DO UPDATE SET created_at = NOW()
+		WHERE reward_idempotency_keys.created_at <= NOW() - make_interval(secs => $2)
+	`, key, idempotencyTTL.Seconds())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		if err == nil {
+			err = ErrDuplicateRequest
+		}
+		return err
+	}
+	return nil
+}
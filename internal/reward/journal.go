@@ -0,0 +1,122 @@
+package reward
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/angad363/stocky-assignment/internal/accounting"
+	"github.com/jmoiron/sqlx"
+)
+
+// ledgerJournal books a ledger posting in double entry. Shares bought for
+// a reward sit in stock inventory against the user's holdings account.
+// Purchases leave STT and GST owed until the trade settles; fee
+// adjustments come from contract notes after settlement and are paid
+// straight from cash.
+func ledgerJournal(entry LedgerEntry, entryID, userID int, taxesPayable bool) accounting.Journal {
+	inr := accounting.CommodityINR
+	taxes := entry.STT + entry.GST
+	cash := entry.CashOutflow + entry.BrokerageFee + entry.OtherFees
+	if !taxesPayable {
+		cash += taxes
+		taxes = 0
+	}
+
+	lines := []accounting.Line{
+		{Account: accounting.AccountRewards, Commodity: inr, Amount: entry.CashOutflow},
+		{Account: accounting.AccountBrokerage, Commodity: inr, Amount: entry.BrokerageFee},
+		{Account: accounting.AccountOtherFees, Commodity: inr, Amount: entry.OtherFees},
+		{Account: accounting.AccountSTT, Commodity: inr, Amount: entry.STT},
+		{Account: accounting.AccountGST, Commodity: inr, Amount: entry.GST},
+		{Account: accounting.AccountCash, Commodity: inr, Amount: -cash},
+		{Account: accounting.AccountTaxPayable, Commodity: inr, Amount: -taxes},
+	}
+	if entry.StockUnits != 0 {
+		lines = append(lines,
+			accounting.Line{Account: accounting.AccountStockInventory, Commodity: entry.StockSymbol, Amount: entry.StockUnits},
+			accounting.Line{Account: accounting.UserHoldings(userID), Commodity: entry.StockSymbol, Amount: -entry.StockUnits},
+		)
+	}
+
+	description := entry.EntryType + " " + entry.StockSymbol
+	if entry.RewardID != nil {
+		description += " for reward " + strconv.Itoa(*entry.RewardID)
+	}
+	return accounting.Journal{
+		Kind:          entry.EntryType,
+		RewardID:      entry.RewardID,
+		LedgerEntryID: &entryID,
+		Description:   description,
+		PostedAt:      entry.CreatedAt,
+		Lines:         lines,
+	}
+}
+
+// postSettlement pays the STT and GST still owed for a reward once its
+// trade settles
+func postSettlement(ctx context.Context, tx *sqlx.Tx, reward Reward) error {
+	owed, err := accounting.RewardBalance(ctx, tx, reward.ID, accounting.AccountTaxPayable, accounting.CommodityINR)
+	if err != nil || owed == 0 {
+		return err
+	}
+
+	_, err = accounting.Post(ctx, tx, accounting.Journal{
+		Kind:        accounting.KindSettlement,
+		RewardID:    &reward.ID,
+		Description: "settlement of reward " + strconv.Itoa(reward.ID),
+		PostedAt:    reward.StatusUpdatedAt,
+		Lines: []accounting.Line{
+			{Account: accounting.AccountTaxPayable, Commodity: accounting.CommodityINR, Amount: -owed},
+			{Account: accounting.AccountCash, Commodity: accounting.CommodityINR, Amount: owed},
+		},
+	})
+	return err
+}
+
+// rewardOwner returns the user a ledger entry's shares belong to
+func rewardOwner(ctx context.Context, q sqlx.QueryerContext, rewardID *int) (int, error) {
+	if rewardID == nil {
+		return 0, nil
+	}
+	var userID int
+	err := sqlx.GetContext(ctx, q, &userID, `SELECT user_id FROM rewards WHERE id = $1`, *rewardID)
+	return userID, err
+}
+
+// BackfillJournals posts journals for ledger entries written before the
+// double-entry books existed. All their fees are treated as paid in cash.
+func BackfillJournals(ctx context.Context, db *sqlx.DB) (int, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	entries := []LedgerEntry{}
+	err = tx.SelectContext(ctx, &entries, `
+		SELECT id, reward_id, reward_event_id, entry_type, stock_symbol, stock_units,
+		       cash_outflow, brokerage_fee, stt, gst, other_fees, created_at
+		FROM ledger_entries e
+		WHERE NOT EXISTS (SELECT 1 FROM journals j WHERE j.ledger_entry_id = e.id)
+		ORDER BY id
+	`)
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		entryID := entry.ID
+		if entry.EntryType == "reversal" && entry.RewardID != nil {
+			_, err = accounting.Reverse(ctx, tx, *entry.RewardID, &entryID, entry.CreatedAt, "backfilled reversal")
+		} else {
+			var userID int
+			if userID, err = rewardOwner(ctx, tx, entry.RewardID); err == nil {
+				_, err = accounting.Post(ctx, tx, ledgerJournal(entry, entryID, userID, false))
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(entries), tx.Commit()
+}
@@ -0,0 +1,113 @@
+package reward
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// Rewards for users without verified KYC are held: they are bought and
+// settled as usual but are not usable until verification releases them.
+// Holds still open after the hold period expire and are clawed back.
+
+// ReleaseKYCHolds releases a user's held rewards inside the caller's
+// transaction and returns the released reward IDs
+func ReleaseKYCHolds(ctx context.Context, tx *sqlx.Tx, userID int) ([]int, error) {
+	released := []int{}
+	err := tx.SelectContext(ctx, &released, `
+		UPDATE rewards SET kyc_hold = FALSE, kyc_released_at = NOW()
+		WHERE user_id = $1 AND kyc_hold AND status NOT IN ('failed', 'reversed')
+		RETURNING id
+	`, userID)
+	return released, err
+}
+
+// ExpireKYCHolds reverses held rewards whose hold period has passed. Each
+// reward is handled in its own transaction; ordered rewards are left for a
+// later run, once the broker has filled or requeued them.
+func (s *RewardService) ExpireKYCHolds(ctx context.Context, limit int) (int, error) {
+	ids := []int{}
+	err := s.db.SelectContext(ctx, &ids, `
+		SELECT id FROM rewards
+		WHERE kyc_hold AND kyc_expires_at <= NOW() AND status IN ($1, $2, $3)
+		ORDER BY kyc_expires_at
+		LIMIT $4
+	`, StatusPending, StatusPurchased, StatusSettled, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		ok, err := s.expireKYCHold(ctx, id)
+		if err != nil {
+			return expired, fmt.Errorf("reward %d: %w", id, err)
+		}
+		if ok {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+func (s *RewardService) expireKYCHold(ctx context.Context, rewardID int) (bool, error) {
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	reward, err := LockReward(ctx, tx.Tx, rewardID)
+	if err != nil {
+		return false, err
+	}
+	// Released or reversed since it was selected
+	if !reward.KYCHold || reward.KYCExpiresAt == nil || reward.KYCExpiresAt.After(time.Now()) ||
+		!canTransition(reward.Status, StatusReversed) {
+		return false, nil
+	}
+	before := reward
+
+	eventID, err := Transition(ctx, tx.Tx, &reward, StatusReversed, "KYC not completed before the hold expired")
+	if err != nil {
+		return false, err
+	}
+	if err := postReversal(ctx, tx.Tx, reward, eventID, reward.StatusUpdatedAt); err != nil {
+		return false, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{
+		Action:     audit.ActionRewardExpired,
+		EntityType: "reward",
+		EntityID:   strconv.Itoa(reward.ID),
+		Before:     before,
+		After:      reward,
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// StartKYCExpiry periodically claws back rewards whose KYC hold expired
+func StartKYCExpiry(service *RewardService, interval time.Duration) {
+	logger.Log.WithField("interval", interval.String()).Info("🪪 KYC hold expiry started")
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			expired, err := service.ExpireKYCHolds(context.Background(), 500)
+			if err != nil {
+				logger.Log.Errorf("KYC hold expiry failed: %v", err)
+			}
+			if expired > 0 {
+				logger.Log.WithField("rewards", expired).Info("Expired KYC-held rewards clawed back")
+			}
+		}
+	}()
+}
@@ -0,0 +1,352 @@
+package reward
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/accounting"
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/angad363/stocky-assignment/internal/events"
+	"github.com/angad363/stocky-assignment/internal/holdings"
+	"github.com/angad363/stocky-assignment/internal/tax"
+	"github.com/jmoiron/sqlx"
+)
+
+// Reward lifecycle: shares are bought after the reward is granted and
+// settle T+1, so a reward only becomes withdrawable once settled.
+const (
+	StatusPending   = "pending"
+	StatusOrdered   = "ordered"
+	StatusPurchased = "purchased"
+	StatusSettled   = "settled"
+	StatusFailed    = "failed"
+	StatusReversed  = "reversed"
+)
+
+var (
+	ErrRewardNotFound    = errors.New("reward not found")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrUserInactive      = errors.New("user is deactivated")
+	ErrUnknownCampaign   = errors.New("unknown campaign")
+	ErrInvalidTransition = errors.New("invalid reward status transition")
+	ErrPriceRequired     = errors.New("execution price is required when marking a reward purchased")
+	ErrOrderInFlight     = errors.New("reward is on a broker order awaiting its outcome")
+	ErrPartlyFilled      = errors.New("reward already has shares allocated by the broker")
+)
+
+// transitions lists the statuses each status may move to
+var transitions = map[string][]string{
+	StatusPending:   {StatusOrdered, StatusFailed, StatusReversed},
+	StatusOrdered:   {StatusPurchased, StatusFailed, StatusPending, StatusReversed},
+	StatusPurchased: {StatusSettled, StatusReversed},
+	StatusSettled:   {StatusReversed},
+	StatusFailed:    {StatusPending},
+	StatusReversed:  {},
+}
+
+// countsAsHolding reports whether a reward in the status is part of the
+// user's holdings
+func countsAsHolding(status string) bool {
+	return status != StatusFailed && status != StatusReversed
+}
+
+func canTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionReward moves a reward to a new status, records the event and
+// posts the matching ledger entries in a single transaction
+func (s *RewardService) TransitionReward(ctx context.Context, rewardID int, req TransitionRequest) (Reward, error) {
+	var reward Reward
+
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return reward, err
+	}
+	defer tx.Rollback()
+
+	reward, err = LockReward(ctx, tx.Tx, rewardID)
+	if err != nil {
+		return reward, err
+	}
+	if req.Status == StatusPurchased && req.ExecutionPrice <= 0 {
+		return reward, ErrPriceRequired
+	}
+
+	// The broker batch owns rewards on a submitted order until it records
+	// the fill, and shares it has already allocated stay posted, so a
+	// partly filled reward can only be completed or reversed. Reversal
+	// negates the allocated fills along with the rest of its postings.
+	if reward.Status == StatusOrdered {
+		var inFlight bool
+		err = tx.GetContext(ctx, &inFlight, `
+			SELECT EXISTS (
+				SELECT 1 FROM broker_order_items i
+				JOIN broker_orders o ON o.id = i.order_id
+				WHERE i.reward_id = $1 AND o.status = 'submitted'
+			)
+		`, reward.ID)
+		if err != nil {
+			return reward, err
+		}
+		if inFlight {
+			return reward, ErrOrderInFlight
+		}
+	}
+	allocated, err := allocatedUnits(ctx, tx.Tx, reward.ID)
+	if err != nil {
+		return reward, err
+	}
+	if allocated > 0 && (req.Status == StatusFailed || req.Status == StatusPending) {
+		return reward, fmt.Errorf("%w: %v of %v units bought, cannot move to %s",
+			ErrPartlyFilled, allocated, reward.Quantity, req.Status)
+	}
+	before := reward
+
+	eventID, err := Transition(ctx, tx.Tx, &reward, req.Status, req.Note)
+	if err != nil {
+		return reward, err
+	}
+
+	action := audit.ActionRewardStatusChanged
+	if req.Status == StatusReversed {
+		action = audit.ActionRewardReversed
+	}
+	err = audit.Record(ctx, tx, audit.Entry{
+		Action:     action,
+		EntityType: "reward",
+		EntityID:   strconv.Itoa(reward.ID),
+		Before:     before,
+		After:      map[string]interface{}{"reward": reward, "note": req.Note, "execution_price": req.ExecutionPrice},
+	})
+	if err != nil {
+		return reward, err
+	}
+
+	switch req.Status {
+	case StatusPurchased:
+		err = postPurchase(ctx, tx.Tx, reward, eventID, reward.Quantity-allocated, req.ExecutionPrice, reward.StatusUpdatedAt)
+	case StatusSettled:
+		err = postSettlement(ctx, tx.Tx, reward)
+	case StatusReversed:
+		err = postReversal(ctx, tx.Tx, reward, eventID, reward.StatusUpdatedAt)
+	}
+	if err != nil {
+		return reward, err
+	}
+
+	return reward, tx.Commit()
+}
+
+// LockReward loads a reward with a row lock for the rest of the transaction
+func LockReward(ctx context.Context, tx *sqlx.Tx, rewardID int) (Reward, error) {
+	var reward Reward
+	err := tx.GetContext(ctx, &reward, `
+		SELECT `+rewardColumns+`
+		FROM rewards
+		WHERE id = $1
+		FOR UPDATE
+	`, rewardID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return reward, ErrRewardNotFound
+	}
+	return reward, err
+}
+
+// Transition validates and applies a status change to a locked reward and
+// records the event. Ledger postings are left to the caller; the event ID
+// is returned so they can reference it.
+func Transition(ctx context.Context, tx *sqlx.Tx, reward *Reward, to, note string) (int, error) {
+	if !canTransition(reward.Status, to) {
+		return 0, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, reward.Status, to)
+	}
+
+	now := time.Now()
+	eventID, err := recordEvent(ctx, tx, reward.ID, reward.Status, to, note, now)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE rewards SET status = $2, status_updated_at = $3 WHERE id = $1
+	`, reward.ID, to, now)
+	if err != nil {
+		return 0, err
+	}
+
+	// Failed and reversed rewards drop out of the user's holding, and a
+	// failed reward retried goes back in
+	if countsAsHolding(reward.Status) != countsAsHolding(to) {
+		cost := 0.0
+		if reward.GrantPrice != nil {
+			cost = reward.Quantity * *reward.GrantPrice
+		}
+		if countsAsHolding(to) {
+			err = holdings.Add(ctx, tx, reward.UserID, reward.StockSymbol, reward.Quantity, cost)
+		} else {
+			err = holdings.Remove(ctx, tx, reward.UserID, reward.StockSymbol, reward.Quantity, cost)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	reward.Status = to
+	reward.StatusUpdatedAt = now
+
+	if to == StatusReversed {
+		if err := events.Write(ctx, tx, events.RewardReversed, reward.UserID, reward); err != nil {
+			return 0, err
+		}
+	}
+	return eventID, nil
+}
+
+// PostLedgerEntry writes a single ledger row and its balanced journal
+// inside the caller's transaction
+func PostLedgerEntry(ctx context.Context, tx *sqlx.Tx, entry LedgerEntry) error {
+	var entryID int
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO ledger_entries
+			(reward_id, reward_event_id, entry_type, stock_symbol, stock_units,
+			 cash_outflow, brokerage_fee, stt, gst, other_fees, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`, entry.RewardID, entry.RewardEventID, entry.EntryType, entry.StockSymbol, entry.StockUnits,
+		entry.CashOutflow, entry.BrokerageFee, entry.STT, entry.GST, entry.OtherFees, entry.CreatedAt).Scan(&entryID)
+	if err != nil {
+		return err
+	}
+
+	userID, err := rewardOwner(ctx, tx, entry.RewardID)
+	if err != nil {
+		return err
+	}
+	_, err = accounting.Post(ctx, tx, ledgerJournal(entry, entryID, userID, entry.EntryType == "purchase"))
+	return err
+}
+
+// GetRewardHistory returns the reward with its status events and ledger postings
+func (s *RewardService) GetRewardHistory(ctx context.Context, rewardID int) (RewardHistory, error) {
+	var history RewardHistory
+
+	err := s.db.GetContext(ctx, &history.Reward, `
+		SELECT `+rewardColumns+` FROM rewards WHERE id = $1
+	`, rewardID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return history, ErrRewardNotFound
+	}
+	if err != nil {
+		return history, err
+	}
+
+	history.Events = []RewardEvent{}
+	err = s.db.SelectContext(ctx, &history.Events, `
+		SELECT id, reward_id, from_status, to_status, note, created_at
+		FROM reward_events
+		WHERE reward_id = $1
+		ORDER BY created_at, id
+	`, rewardID)
+	if err != nil {
+		return history, err
+	}
+
+	history.Ledger = []LedgerEntry{}
+	err = s.db.SelectContext(ctx, &history.Ledger, `
+		SELECT id, reward_id, reward_event_id, entry_type, stock_symbol, stock_units,
+		       cash_outflow, brokerage_fee, stt, gst, other_fees, created_at
+		FROM ledger_entries
+		WHERE reward_id = $1
+		ORDER BY created_at, id
+	`, rewardID)
+	return history, err
+}
+
+func recordEvent(ctx context.Context, tx *sqlx.Tx, rewardID int, from, to, note string, at time.Time) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO reward_events (reward_id, from_status, to_status, note, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, rewardID, from, to, note, at).Scan(&id)
+	return id, err
+}
+
+// allocatedUnits is how much of a reward the broker has already bought
+func allocatedUnits(ctx context.Context, tx *sqlx.Tx, rewardID int) (float64, error) {
+	var units float64
+	err := tx.GetContext(ctx, &units, `
+		SELECT COALESCE(SUM(quantity), 0) FROM broker_allocations WHERE reward_id = $1
+	`, rewardID)
+	return units, err
+}
+
+// postPurchase books the stock units acquired and the cash spent on them.
+// Units the broker already allocated were posted with their fill, so only
+// the remainder is booked here.
+func postPurchase(ctx context.Context, tx *sqlx.Tx, reward Reward, eventID int, units, price float64, at time.Time) error {
+	units = math.Round(units*1e6) / 1e6
+	if units <= 0 {
+		return nil
+	}
+	fees := EstimateFees(units * price)
+	return PostLedgerEntry(ctx, tx, LedgerEntry{
+		RewardID:      &reward.ID,
+		RewardEventID: &eventID,
+		EntryType:     "purchase",
+		StockSymbol:   reward.StockSymbol,
+		StockUnits:    units,
+		CashOutflow:   fees.CashOutflow,
+		BrokerageFee:  fees.Brokerage,
+		STT:           fees.STT,
+		GST:           fees.GST,
+		CreatedAt:     at,
+	})
+}
+
+// postReversal negates whatever has already been posted for the reward,
+// in the ledger and in the journals, and takes the shares back out of the
+// user's tax lots
+func postReversal(ctx context.Context, tx *sqlx.Tx, reward Reward, eventID int, at time.Time) error {
+	err := tax.ConsumeLots(ctx, tx, tax.Disposal{
+		Kind:        tax.DisposalReversal,
+		UserID:      reward.UserID,
+		StockSymbol: reward.StockSymbol,
+		Quantity:    reward.Quantity,
+		RewardID:    &reward.ID,
+		DisposedAt:  at,
+	})
+	if err != nil {
+		return err
+	}
+
+	// No row is written when nothing was posted yet, e.g. a pending reward
+	var entryID *int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO ledger_entries
+			(reward_id, reward_event_id, entry_type, stock_symbol, stock_units,
+			 cash_outflow, brokerage_fee, stt, gst, other_fees, created_at)
+		SELECT $1, $2, 'reversal', $3,
+		       -SUM(stock_units), -SUM(cash_outflow), -SUM(brokerage_fee), -SUM(stt), -SUM(gst), -SUM(other_fees), $4
+		FROM ledger_entries
+		WHERE reward_id = $1
+		HAVING COUNT(*) > 0
+		RETURNING id
+	`, reward.ID, eventID, reward.StockSymbol, at).Scan(&entryID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	_, err = accounting.Reverse(ctx, tx, reward.ID, entryID, at, "reversal of reward "+strconv.Itoa(reward.ID))
+	return err
+}
@@ -0,0 +1,100 @@
+package reward
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListRewards pages through rewards ordered by (rewarded_at, id). Total
+// counts every reward matching the filter, ignoring the cursor.
+func (s *RewardService) ListRewards(ctx context.Context, f RewardFilter) (RewardPage, error) {
+	page := RewardPage{Rewards: []Reward{}}
+
+	if f.Cursor != nil && f.Cursor.Desc != f.Desc {
+		return page, ErrInvalidCursor
+	}
+	if f.UserID != nil {
+		if err := s.ensureUser(ctx, *f.UserID); err != nil {
+			return page, err
+		}
+	}
+
+	where := `
+		WHERE ($1::int IS NULL OR user_id = $1)
+		  AND ($2 = '' OR stock_symbol = $2)
+		  AND ($3 = '' OR campaign = $3)
+		  AND ($4 = '' OR status = $4)
+		  AND ($5::timestamptz IS NULL OR rewarded_at >= $5)
+		  AND ($6::timestamptz IS NULL OR rewarded_at < $6)`
+	args := []interface{}{f.UserID, strings.ToUpper(f.Symbol), f.Campaign, f.Status, f.From, f.To}
+
+	if err := s.db.GetContext(ctx, &page.Total, `SELECT COUNT(*) FROM rewards`+where, args...); err != nil {
+		return page, err
+	}
+
+	order, after := "ASC", ">"
+	if f.Desc {
+		order, after = "DESC", "<"
+	}
+	var cursorAt *time.Time
+	cursorID := 0
+	if f.Cursor != nil {
+		cursorAt, cursorID = &f.Cursor.RewardedAt, f.Cursor.ID
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	query := `SELECT ` + rewardColumns + ` FROM rewards` + where + `
+		  AND ($7::timestamptz IS NULL OR (rewarded_at, id) ` + after + ` ($7, $8))
+		ORDER BY rewarded_at ` + order + `, id ` + order + `
+		LIMIT $9`
+	args = append(args, cursorAt, cursorID, f.Limit+1)
+	if err := s.db.SelectContext(ctx, &page.Rewards, query, args...); err != nil {
+		return page, err
+	}
+
+	if len(page.Rewards) > f.Limit {
+		page.Rewards = page.Rewards[:f.Limit]
+		last := page.Rewards[f.Limit-1]
+		page.NextCursor = EncodeCursor(RewardCursor{RewardedAt: last.RewardedAt, ID: last.ID, Desc: f.Desc})
+	}
+	return page, nil
+}
+
+// EncodeCursor makes an opaque page token. The sort direction is part of
+// it so a cursor cannot be replayed against the opposite order.
+func EncodeCursor(c RewardCursor) string {
+	dir := "a"
+	if c.Desc {
+		dir = "d"
+	}
+	raw := fmt.Sprintf("%s|%s|%d", dir, c.RewardedAt.UTC().Format(time.RFC3339Nano), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(token string) (RewardCursor, error) {
+	var c RewardCursor
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || (parts[0] != "a" && parts[0] != "d") {
+		return c, ErrInvalidCursor
+	}
+	c.Desc = parts[0] == "d"
+	if c.RewardedAt, err = time.Parse(time.RFC3339Nano, parts[1]); err != nil {
+		return c, ErrInvalidCursor
+	}
+	if c.ID, err = strconv.Atoi(parts[2]); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,43 @@
+package reward
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	at := time.Date(2025, time.November, 10, 9, 30, 15, 123456000, time.FixedZone("IST", 5*3600+1800))
+	for _, desc := range []bool{false, true} {
+		in := RewardCursor{RewardedAt: at, ID: 42, Desc: desc}
+		out, err := DecodeCursor(EncodeCursor(in))
+		if err != nil {
+			t.Fatalf("desc=%v: %v", desc, err)
+		}
+		if !out.RewardedAt.Equal(in.RewardedAt) || out.ID != in.ID || out.Desc != in.Desc {
+			t.Errorf("desc=%v: decoded %+v, want %+v", desc, out, in)
+		}
+	}
+}
+
+func TestDecodeCursorRejectsTamperedTokens(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	for name, token := range map[string]string{
+		"empty":             "",
+		"not base64":        "not a cursor!",
+		"padded base64":     base64.URLEncoding.EncodeToString([]byte("a|2025-11-10T04:00:15Z|77")),
+		"unknown direction": encode("x|2025-11-10T04:00:15Z|7"),
+		"missing part":      encode("a|2025-11-10T04:00:15Z"),
+		"extra part":        encode("a|2025-11-10T04:00:15Z|7|1"),
+		"date only":         encode("a|2025-11-10|7"),
+		"bad id":            encode("a|2025-11-10T04:00:15Z|seven"),
+	} {
+		if _, err := DecodeCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: err = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
@@ -7,16 +7,154 @@ type Reward struct {
 	UserID      int       `db:"user_id" json:"user_id"`
 	StockSymbol string    `db:"stock_symbol" json:"stock_symbol"`
 	Quantity    float64   `db:"quantity" json:"quantity"`
+	Campaign    string    `db:"campaign" json:"campaign"`
 	RewardedAt  time.Time `db:"rewarded_at" json:"rewarded_at"`
+
+	// Fair market value per share at grant; the recipient's taxable perquisite.
+	// Nil for rewards granted before prices were captured.
+	GrantPrice *float64 `db:"grant_price" json:"grant_price"`
+
+	// Shares are bought at the next session when rewarded outside market hours
+	PurchaseAfter  time.Time `db:"purchase_after" json:"purchase_after"`
+	OffMarketHours bool      `db:"off_market_hours" json:"off_market_hours"`
+
+	Status          string    `db:"status" json:"status"`
+	StatusUpdatedAt time.Time `db:"status_updated_at" json:"status_updated_at"`
+
+	// Held until the user's KYC is verified; clawed back after KYCExpiresAt
+	KYCHold       bool       `db:"kyc_hold" json:"kyc_hold"`
+	KYCExpiresAt  *time.Time `db:"kyc_expires_at" json:"kyc_expires_at,omitempty"`
+	KYCReleasedAt *time.Time `db:"kyc_released_at" json:"kyc_released_at,omitempty"`
+}
+
+// rewardColumns is the column list matching Reward
+const rewardColumns = `id, user_id, stock_symbol, quantity, campaign, rewarded_at, grant_price,
+	COALESCE(purchase_after, rewarded_at) AS purchase_after, off_market_hours,
+	status, status_updated_at, kyc_hold, kyc_expires_at, kyc_released_at`
+
+// Campaigns under which rewards are issued. API keys may be limited to some.
+const (
+	CampaignManual     = "manual"
+	CampaignOnboarding = "onboarding"
+	CampaignReferral   = "referral"
+)
+
+// ValidCampaign reports whether rewards can be issued under campaign
+func ValidCampaign(campaign string) bool {
+	switch campaign {
+	case CampaignManual, CampaignOnboarding, CampaignReferral:
+		return true
+	}
+	return false
 }
 
 type RewardRequest struct {
 	UserID   int     `json:"user_id"`
 	Symbol   string  `json:"symbol,omitempty"`
 	Quantity float64 `json:"quantity"`
+	Campaign string  `json:"campaign,omitempty"` // defaults to manual
+
+	// IdempotencyKey is claimed by the transaction that acts on the
+	// request; it comes from the Idempotency-Key header
+	IdempotencyKey string `json:"-"`
+}
+
+type TransitionRequest struct {
+	Status         string  `json:"status" binding:"required"`
+	Note           string  `json:"note"`
+	ExecutionPrice float64 `json:"execution_price,omitempty"` // required for purchased
+}
+
+type RewardEvent struct {
+	ID         int       `db:"id" json:"id"`
+	RewardID   int       `db:"reward_id" json:"reward_id"`
+	FromStatus string    `db:"from_status" json:"from_status"`
+	ToStatus   string    `db:"to_status" json:"to_status"`
+	Note       string    `db:"note" json:"note"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+type LedgerEntry struct {
+	ID            int       `db:"id" json:"id"`
+	RewardID      *int      `db:"reward_id" json:"reward_id"`
+	RewardEventID *int      `db:"reward_event_id" json:"reward_event_id"`
+	EntryType     string    `db:"entry_type" json:"entry_type"`
+	StockSymbol   string    `db:"stock_symbol" json:"stock_symbol"`
+	StockUnits    float64   `db:"stock_units" json:"stock_units"`
+	CashOutflow   float64   `db:"cash_outflow" json:"cash_outflow"`
+	BrokerageFee  float64   `db:"brokerage_fee" json:"brokerage_fee"`
+	STT           float64   `db:"stt" json:"stt"`
+	GST           float64   `db:"gst" json:"gst"`
+	OtherFees     float64   `db:"other_fees" json:"other_fees"` // exchange, SEBI and stamp charges
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+}
+
+type RewardHistory struct {
+	Reward Reward        `json:"reward"`
+	Events []RewardEvent `json:"events"`
+	Ledger []LedgerEntry `json:"ledger"`
 }
 
 type HistoricalINR struct {
-	Date     string  `json:"date"`
-	TotalINR float64 `json:"total_inr"`
+	Date       string  `json:"date"`
+	TotalINR   float64 `json:"total_inr"`
+	ValuedAsOf string  `json:"valued_as_of"` // trading session used for the valuation
+}
+
+// RewardApproval is a manual reward held for a second admin's decision
+type RewardApproval struct {
+	ID                  int                `db:"id" json:"id"`
+	UserID              int                `db:"user_id" json:"user_id"`
+	StockSymbol         string             `db:"stock_symbol" json:"stock_symbol"`
+	Quantity            float64            `db:"quantity" json:"quantity"`
+	Campaign            string             `db:"campaign" json:"campaign"`
+	PriceAtRequest      float64            `db:"price_at_request" json:"price_at_request"`
+	ValueINR            float64            `db:"value_inr" json:"value_inr"`
+	Status              string             `db:"status" json:"status"`
+	RequestedByUserID   *int               `db:"requested_by_user_id" json:"requested_by_user_id"`
+	RequestedByAPIKeyID *int               `db:"requested_by_api_key_id" json:"requested_by_api_key_id"`
+	RequestedAt         time.Time          `db:"requested_at" json:"requested_at"`
+	DecidedAt           *time.Time         `db:"decided_at" json:"decided_at"`
+	RewardID            *int               `db:"reward_id" json:"reward_id"`
+	Decisions           []ApprovalDecision `db:"-" json:"decisions,omitempty"`
+}
+
+type ApprovalDecision struct {
+	ID            int       `db:"id" json:"id"`
+	ApprovalID    int       `db:"approval_id" json:"approval_id"`
+	ActorUserID   *int      `db:"actor_user_id" json:"actor_user_id"`
+	ActorAPIKeyID *int      `db:"actor_api_key_id" json:"actor_api_key_id"`
+	Action        string    `db:"action" json:"action"`
+	Comment       string    `db:"comment" json:"comment"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+}
+
+type DecisionRequest struct {
+	Comment string `json:"comment"`
+}
+
+// RewardFilter narrows GET /rewards; zero values match everything
+type RewardFilter struct {
+	UserID   *int
+	Symbol   string
+	Campaign string
+	Status   string
+	From     *time.Time
+	To       *time.Time // exclusive
+	Desc     bool
+	Cursor   *RewardCursor
+	Limit    int
+}
+
+// RewardCursor is the (rewarded_at, id) position after which a page starts
+type RewardCursor struct {
+	RewardedAt time.Time
+	ID         int
+	Desc       bool
+}
+
+type RewardPage struct {
+	Rewards    []Reward `json:"rewards"`
+	Total      int      `json:"total"`
+	NextCursor string   `json:"next_cursor,omitempty"`
 }
@@ -2,78 +2,224 @@ package reward
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"math"
-	"math/rand"
+	"strconv"
 	"time"
 
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/angad363/stocky-assignment/internal/calendar"
+	"github.com/angad363/stocky-assignment/internal/events"
+	"github.com/angad363/stocky-assignment/internal/holdings"
+	"github.com/angad363/stocky-assignment/internal/instruments"
 	"github.com/angad363/stocky-assignment/internal/price"
+	"github.com/angad363/stocky-assignment/internal/tax"
 	"github.com/jmoiron/sqlx"
 )
 
 type RewardService struct {
-	db       *sqlx.DB
-	priceSvc *price.PriceService
+	db            *sqlx.DB
+	priceSvc      *price.PriceService
+	instrumentSvc *instruments.InstrumentService
+	calendar      *calendar.Calendar
+	kycHold       time.Duration
 }
 
-func NewRewardService(db *sqlx.DB, priceSvc *price.PriceService) *RewardService {
-	return &RewardService{db: db, priceSvc: priceSvc}
+// NewRewardService creates the service. Rewards for users without verified
+// KYC are held for kycHold before they are clawed back.
+func NewRewardService(db *sqlx.DB, priceSvc *price.PriceService, instrumentSvc *instruments.InstrumentService, cal *calendar.Calendar, kycHold time.Duration) *RewardService {
+	return &RewardService{db: db, priceSvc: priceSvc, instrumentSvc: instrumentSvc, calendar: cal, kycHold: kycHold}
 }
 
 func (s *RewardService) CreateReward(ctx context.Context, req RewardRequest) (Reward, error) {
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return Reward{}, err
+	}
+	defer tx.Rollback()
+
+	if err := claimIdempotencyKey(ctx, tx.Tx, req.IdempotencyKey); err != nil {
+		return Reward{}, err
+	}
+	reward, err := s.CreateRewardTx(ctx, tx, req)
+	if err != nil {
+		return reward, err
+	}
+	return reward, tx.Commit()
+}
+
+// CreateRewardTx writes a reward, its first lifecycle event and its outbox
+// event inside the caller's transaction
+func (s *RewardService) CreateRewardTx(ctx context.Context, tx *audit.Tx, req RewardRequest) (Reward, error) {
 	var reward Reward
 
-	symbol := req.Symbol
-	if symbol == "" {
-		stocks := []string{"RELIANCE", "TCS", "INFY", "HDFC", "ICICIBANK"}
-		symbol = stocks[rand.Intn(len(stocks))]
+	verified, err := checkRecipient(ctx, tx, req.UserID)
+	if err != nil {
+		return reward, err
 	}
 
-	// we call the price service just to simulate reward logic,
-	// though we don’t store the price in DB
-	_, err := s.priceSvc.GetStockPrice(symbol)
+	// Pick a random active instrument when no symbol is given,
+	// otherwise the symbol must exist in the instrument master
+	var inst instruments.Instrument
+	if req.Symbol == "" {
+		inst, err = s.instrumentSvc.RandomActive(ctx)
+		if err == nil {
+			inst, err = s.instrumentSvc.ValidateReward(ctx, inst.Symbol, req.Quantity)
+		}
+	} else {
+		inst, err = s.instrumentSvc.ValidateReward(ctx, req.Symbol, req.Quantity)
+	}
 	if err != nil {
 		return reward, err
 	}
+	symbol := inst.Symbol
 
+	// The quote at grant is the fair market value, which becomes the
+	// cost basis of the reward's tax lot
+	quote, err := s.priceSvc.GetStockPrice(symbol)
+	if err != nil {
+		return reward, err
+	}
+
+	campaign := req.Campaign
+	if campaign == "" {
+		campaign = CampaignManual
+	}
+	if !ValidCampaign(campaign) {
+		return reward, fmt.Errorf("%w %q", ErrUnknownCampaign, campaign)
+	}
+
+	now := time.Now()
+	grantPrice := math.Round(quote.Price*10000) / 10000
 	reward = Reward{
-		UserID:      req.UserID,
-		StockSymbol: symbol,
-		Quantity:    req.Quantity,
-		RewardedAt:  time.Now(),
+		UserID:          req.UserID,
+		StockSymbol:     symbol,
+		Quantity:        req.Quantity,
+		Campaign:        campaign,
+		RewardedAt:      now,
+		GrantPrice:      &grantPrice,
+		PurchaseAfter:   s.calendar.NextOpen(now),
+		OffMarketHours:  !s.calendar.IsOpen(now),
+		Status:          StatusPending,
+		StatusUpdatedAt: now,
+	}
+	if !verified {
+		expires := now.Add(s.kycHold)
+		reward.KYCHold = true
+		reward.KYCExpiresAt = &expires
 	}
 
 	query := `
-		INSERT INTO rewards (user_id, stock_symbol, quantity, rewarded_at)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO rewards (user_id, stock_symbol, quantity, campaign, rewarded_at, grant_price, purchase_after,
+		                     off_market_hours, status, status_updated_at, kyc_hold, kyc_expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id
 	`
-	err = s.db.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, query,
 		reward.UserID,
 		reward.StockSymbol,
 		reward.Quantity,
+		reward.Campaign,
 		reward.RewardedAt,
+		reward.GrantPrice,
+		reward.PurchaseAfter,
+		reward.OffMarketHours,
+		reward.Status,
+		reward.StatusUpdatedAt,
+		reward.KYCHold,
+		reward.KYCExpiresAt,
 	).Scan(&reward.ID)
+	if err != nil {
+		return reward, err
+	}
+
+	if _, err = recordEvent(ctx, tx.Tx, reward.ID, "", StatusPending, "reward granted", now); err != nil {
+		return reward, err
+	}
+
+	err = holdings.Add(ctx, tx.Tx, reward.UserID, reward.StockSymbol, reward.Quantity, reward.Quantity*grantPrice)
+	if err != nil {
+		return reward, err
+	}
 
+	err = tax.OpenLot(ctx, tx.Tx, tax.Lot{
+		RewardID:     reward.ID,
+		UserID:       reward.UserID,
+		StockSymbol:  reward.StockSymbol,
+		AcquiredAt:   reward.RewardedAt,
+		Quantity:     reward.Quantity,
+		CostPerShare: grantPrice,
+	})
+	if err != nil {
+		return reward, err
+	}
 
+	err = audit.Record(ctx, tx, audit.Entry{
+		Action:     audit.ActionRewardIssued,
+		EntityType: "reward",
+		EntityID:   strconv.Itoa(reward.ID),
+		After:      reward,
+	})
 	if err != nil {
-		fmt.Println("❌ Insert error:", err)
 		return reward, err
 	}
 
-	return reward, err
+	return reward, events.Write(ctx, tx.Tx, events.RewardCreated, reward.UserID, reward)
+}
+
+// checkRecipient rejects rewards for unknown or deactivated users and
+// reports whether the user's KYC is verified. The user row is share-locked
+// so neither can change before the reward commits.
+func checkRecipient(ctx context.Context, q sqlx.QueryerContext, userID int) (bool, error) {
+	var user struct {
+		Active   bool `db:"active"`
+		Verified bool `db:"verified"`
+	}
+	err := sqlx.GetContext(ctx, q, &user, `
+		SELECT status = 'active' AS active, kyc_status = 'verified' AS verified
+		FROM users WHERE id = $1 FOR SHARE
+	`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrUserNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+	if !user.Active {
+		return false, ErrUserInactive
+	}
+	return user.Verified, nil
+}
+
+// ensureUser lets holdings endpoints tell unknown users from users
+// without rewards
+func (s *RewardService) ensureUser(ctx context.Context, userID int) error {
+	var exists bool
+	err := s.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+	return nil
 }
 
 func (s *RewardService) GetTodayRewards(ctx context.Context, userID int) ([]Reward, error) {
+	if err := s.ensureUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
 	rewards := []Reward{}
 
-	loc, _ := time.LoadLocation("Asia/Kolkata")
-	now := time.Now().In(loc)
-	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	now := time.Now().In(calendar.IST)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, calendar.IST)
 	endOfDay := startOfDay.Add(24 * time.Hour)
 
 	query := `
-		SELECT id, user_id, stock_symbol, quantity, rewarded_at
+		SELECT ` + rewardColumns + `
 		FROM rewards
 		WHERE user_id = $1
 		  AND rewarded_at >= $2
@@ -90,10 +236,15 @@ func (s *RewardService) GetTodayRewards(ctx context.Context, userID int) ([]Rewa
 }
 
 func (s *RewardService) GetHistoricalINR(ctx context.Context, userID int) ([]HistoricalINR, error) {
+	if err := s.ensureUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
 	rows, err := s.db.QueryxContext(ctx, `
 		SELECT stock_symbol, quantity, rewarded_at
 		FROM rewards
 		WHERE user_id = $1
+		  AND status NOT IN ('failed', 'reversed')
 		  AND (rewarded_at AT TIME ZONE 'Asia/Kolkata')::date < CURRENT_DATE
 		ORDER BY rewarded_at ASC
 	`, userID)
@@ -122,15 +273,18 @@ func (s *RewardService) GetHistoricalINR(ctx context.Context, userID int) ([]His
 		inrValue := r.Quantity * price.Price
 		inrValue = math.Round(inrValue*100) / 100
 
-		dateKey := r.RewardedAt.Format("2006-01-02")
+		dateKey := r.RewardedAt.In(calendar.IST).Format("2006-01-02")
 		dateTotals[dateKey] += inrValue
 	}
 
 	var historical []HistoricalINR
 	for date, total := range dateTotals {
+		// end-of-day value uses the last trading session, not the calendar date
+		day, _ := time.ParseInLocation("2006-01-02", date, calendar.IST)
 		historical = append(historical, HistoricalINR{
-			Date:     date,
-			TotalINR: total,
+			Date:       date,
+			TotalINR:   total,
+			ValuedAsOf: s.calendar.SessionDate(day).Format("2006-01-02"),
 		})
 	}
 
@@ -138,10 +292,15 @@ func (s *RewardService) GetHistoricalINR(ctx context.Context, userID int) ([]His
 }
 
 func (s *RewardService) GetUserStats(ctx context.Context, userID int) (map[string]float64, float64, error) {
+	if err := s.ensureUser(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+
 	todayQuery := `
 		SELECT stock_symbol, SUM(quantity) AS total_quantity
 		FROM rewards
 		WHERE user_id = $1
+		AND status NOT IN ('failed', 'reversed')
 		AND DATE(rewarded_at AT TIME ZONE 'Asia/Kolkata') = CURRENT_DATE
 		GROUP BY stock_symbol
 	`
@@ -160,50 +319,62 @@ func (s *RewardService) GetUserStats(ctx context.Context, userID int) (map[strin
 		}
 	}
 
-	holdingsQuery := `
-		SELECT stock_symbol, SUM(quantity) AS total_quantity
-		FROM rewards
-		WHERE user_id = $1
-		GROUP BY stock_symbol
-	`
-	holdRows, err := s.db.QueryxContext(ctx, holdingsQuery, userID)
+	held, err := holdings.ForUser(ctx, s.db, userID)
 	if err != nil {
 		return todaySummary, 0, err
 	}
-	defer holdRows.Close()
 
 	totalValue := 0.0
-	for holdRows.Next() {
-		var symbol string
-		var qty float64
-		if err := holdRows.Scan(&symbol, &qty); err == nil {
-			priceResp, err := s.priceSvc.GetStockPrice(symbol)
-			if err != nil {
-				continue
-			}
-			// handle rounding for INR precision
-			inr := qty * priceResp.Price
-			inr = float64(int(inr*100+0.5)) / 100
-			totalValue += inr
+	for _, h := range held {
+		priceResp, err := s.priceSvc.GetStockPrice(h.StockSymbol)
+		if err != nil {
+			continue
 		}
+		// handle rounding for INR precision
+		inr := h.Quantity * priceResp.Price
+		inr = float64(int(inr*100+0.5)) / 100
+		totalValue += inr
 	}
 	totalValue = math.Round(totalValue*100) / 100
 	return todaySummary, totalValue, nil
 }
 
-// PortfolioItem represents each stock holding for the user
+// PortfolioItem represents each stock holding for the user.
+// Only settled quantity is withdrawable; pending covers rewards that are
+// still being bought or awaiting T+1 settlement.
 type PortfolioItem struct {
-	Symbol   string  `json:"symbol"`
-	Quantity float64 `json:"quantity"`
-	INRValue float64 `json:"inr_value"`
+	Symbol          string  `json:"symbol"`
+	Quantity        float64 `json:"quantity"`
+	SettledQuantity float64 `json:"settled_quantity"`
+	PendingQuantity float64 `json:"pending_quantity"`
+	KYCHeldQuantity float64 `json:"kyc_held_quantity"` // released once KYC is verified
+	INRValue        float64 `json:"inr_value"`
 }
 
 func (s *RewardService) GetUserPortfolio(ctx context.Context, userID int) ([]PortfolioItem, error) {
+	if err := s.ensureUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// Totals come from the holdings table; only rewards still being bought
+	// or on KYC hold are read to split out what is not yet withdrawable.
+	// The statuses are spelled out so rewards_user_open_idx applies.
 	query := `
-		SELECT stock_symbol, SUM(quantity) AS total_quantity
-		FROM rewards
-		WHERE user_id = $1
-		GROUP BY stock_symbol
+		SELECT h.stock_symbol, h.quantity,
+		       COALESCE(o.pending_quantity, 0) AS pending_quantity,
+		       COALESCE(o.held_quantity, 0) AS held_quantity
+		FROM holdings h
+		LEFT JOIN (
+			SELECT stock_symbol,
+			       SUM(quantity) FILTER (WHERE status IN ('pending', 'ordered', 'purchased') AND NOT kyc_hold) AS pending_quantity,
+			       SUM(quantity) FILTER (WHERE kyc_hold AND status NOT IN ('failed', 'reversed')) AS held_quantity
+			FROM rewards
+			WHERE user_id = $1
+			AND (status IN ('pending', 'ordered', 'purchased') OR kyc_hold)
+			GROUP BY stock_symbol
+		) o ON o.stock_symbol = h.stock_symbol
+		WHERE h.user_id = $1 AND h.quantity > 0
+		ORDER BY h.stock_symbol
 	`
 	rows, err := s.db.QueryxContext(ctx, query, userID)
 	if err != nil {
@@ -215,10 +386,11 @@ func (s *RewardService) GetUserPortfolio(ctx context.Context, userID int) ([]Por
 
 	for rows.Next() {
 		var symbol string
-		var qty float64
-		if err := rows.Scan(&symbol, &qty); err != nil {
+		var qty, pending, held float64
+		if err := rows.Scan(&symbol, &qty, &pending, &held); err != nil {
 			continue
 		}
+		settled := math.Round((qty-pending-held)*1000000) / 1000000
 
 		priceResp, err := s.priceSvc.GetStockPrice(symbol)
 		if err != nil {
@@ -229,9 +401,12 @@ func (s *RewardService) GetUserPortfolio(ctx context.Context, userID int) ([]Por
 		inrValue = math.Round(inrValue*100) / 100
 
 		portfolio = append(portfolio, PortfolioItem{
-			Symbol:   symbol,
-			Quantity: qty,
-			INRValue: inrValue,
+			Symbol:          symbol,
+			Quantity:        qty,
+			SettledQuantity: settled,
+			PendingQuantity: pending,
+			KYCHeldQuantity: held,
+			INRValue:        inrValue,
 		})
 	}
 
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/angad363/stocky-assignment/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// requestID reuses the caller's X-Request-ID or assigns one, and echoes it
+// so clients and the audit log can refer to the same request
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if id == "" || len(id) > 64 {
+			buf := make([]byte, 16)
+			_, _ = rand.Read(buf)
+			id = hex.EncodeToString(buf)
+		}
+		c.Set("request_id", id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// auditActor puts the caller on the request context for audit.Record. It
+// must run after auth.Identify.
+func auditActor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := audit.Actor{IP: c.ClientIP(), RequestID: c.GetString("request_id")}
+		if principal, ok := auth.FromContext(c); ok {
+			actor.UserID = principal.UserID
+			actor.APIKeyID = principal.APIKeyID
+		}
+		c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// Limit allows Requests per sliding Window
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RoutePolicy holds the limits applied to one route for each kind of
+// caller identity. A zero Limit is not enforced.
+type RoutePolicy struct {
+	IP     Limit
+	User   Limit
+	APIKey Limit
+}
+
+// defaultPolicy names the policy shared by routes without their own
+const defaultPolicy = "*"
+
+// ParseRateLimits reads policies of the form
+//
+//	*=ip:300/1m;POST /reward=ip:60/1m,user:30/1m,key:600/1m
+//
+// Routes are "METHOD /path" as registered with gin, and each route has its
+// own counters. Routes without a policy share the "*" counters.
+func ParseRateLimits(spec string) (map[string]RoutePolicy, error) {
+	policies := map[string]RoutePolicy{}
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, limits, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("rate limit %q: expected route=limits", entry)
+		}
+
+		var policy RoutePolicy
+		for _, part := range strings.Split(limits, ",") {
+			scope, value, ok := strings.Cut(strings.TrimSpace(part), ":")
+			if !ok {
+				return nil, fmt.Errorf("rate limit %q: expected scope:requests/window", part)
+			}
+			limit, err := parseLimit(value)
+			if err != nil {
+				return nil, fmt.Errorf("rate limit %q: %w", part, err)
+			}
+			switch scope {
+			case "ip":
+				policy.IP = limit
+			case "user":
+				policy.User = limit
+			case "key":
+				policy.APIKey = limit
+			default:
+				return nil, fmt.Errorf("rate limit %q: unknown scope %q", part, scope)
+			}
+		}
+		policies[strings.TrimSpace(route)] = policy
+	}
+	return policies, nil
+}
+
+func parseLimit(value string) (Limit, error) {
+	n, w, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("expected requests/window")
+	}
+	requests, err := strconv.Atoi(n)
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid request count %q", n)
+	}
+	window, err := time.ParseDuration(w)
+	if err != nil || window < time.Millisecond {
+		return Limit{}, fmt.Errorf("invalid window %q", w)
+	}
+	return Limit{Requests: requests, Window: window}, nil
+}
+
+// slidingWindow checks every counter first and records the request in all
+// of them only when none is exhausted, so a rejected request does not use
+// up the caller's other budgets. For each key it returns the count and
+// the milliseconds until the oldest request leaves the window. The clock
+// is Redis's own, so app servers with skewed clocks share one window.
+var slidingWindow = redis.NewScript(`
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+local member = ARGV[1]
+local counts = {}
+local allowed = 1
+for i = 1, #KEYS do
+	local window = tonumber(ARGV[2 * i])
+	local limit = tonumber(ARGV[1 + 2 * i])
+	redis.call('ZREMRANGEBYSCORE', KEYS[i], '-inf', now - window)
+	counts[i] = redis.call('ZCARD', KEYS[i])
+	if counts[i] >= limit then
+		allowed = 0
+	end
+end
+local result = {allowed}
+for i = 1, #KEYS do
+	local window = tonumber(ARGV[2 * i])
+	if allowed == 1 then
+		redis.call('ZADD', KEYS[i], now, member)
+		redis.call('PEXPIRE', KEYS[i], window)
+		counts[i] = counts[i] + 1
+	end
+	local reset = window
+	local oldest = redis.call('ZRANGE', KEYS[i], 0, 0, 'WITHSCORES')
+	if oldest[2] then
+		reset = tonumber(oldest[2]) + window - now
+	end
+	table.insert(result, counts[i])
+	table.insert(result, reset)
+end
+return result
+`)
+
+type RateLimiter struct {
+	client   *redis.Client
+	policies map[string]RoutePolicy
+	logger   *logrus.Logger
+}
+
+func NewRateLimiter(client *redis.Client, policies map[string]RoutePolicy, logger *logrus.Logger) *RateLimiter {
+	return &RateLimiter{client: client, policies: policies, logger: logger}
+}
+
+type counter struct {
+	key   string
+	limit Limit
+}
+
+// Middleware enforces the route's policy against the caller's IP, user and
+// API key, and reports the tightest of them in RateLimit-* headers. It
+// must run after auth.Identify. Redis errors let the request through, and
+// the Redis call is bounded by the request's context.
+func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		policy, ok := rl.policies[route]
+		if !ok {
+			route = defaultPolicy
+			policy, ok = rl.policies[defaultPolicy]
+		}
+		if !ok || c.FullPath() == "" {
+			c.Next()
+			return
+		}
+
+		counters := rl.counters(c, route, policy)
+		if len(counters) == 0 {
+			c.Next()
+			return
+		}
+
+		keys := make([]string, len(counters))
+		args := []interface{}{requestMember()}
+		for i, ct := range counters {
+			keys[i] = ct.key
+			args = append(args, ct.limit.Window.Milliseconds(), ct.limit.Requests)
+		}
+
+		res, err := slidingWindow.Run(c.Request.Context(), rl.client, keys, args...).Int64Slice()
+		if c.Request.Context().Err() != nil {
+			// The client has gone; there is no one left to answer
+			c.Abort()
+			return
+		}
+		if err != nil || len(res) != 1+2*len(counters) {
+			rl.logger.WithError(err).WithField("route", route).Warn("Rate limiter unavailable, allowing request")
+			c.Next()
+			return
+		}
+
+		// Report the counter closest to its limit
+		tightest, remaining, resetMs := 0, math.MaxInt, int64(0)
+		for i, ct := range counters {
+			left := ct.limit.Requests - int(res[1+2*i])
+			if left < 0 {
+				left = 0
+			}
+			if left < remaining {
+				tightest, remaining, resetMs = i, left, res[2+2*i]
+			}
+		}
+		limit := counters[tightest].limit
+		resetSecs := int(math.Ceil(float64(resetMs) / 1000))
+
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Window.Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(resetSecs))
+
+		if res[0] == 0 {
+			rl.logger.WithFields(logrus.Fields{
+				"route":   route,
+				"counter": counters[tightest].key,
+			}).Warn("Rate limit exceeded")
+			c.Header("Retry-After", strconv.Itoa(resetSecs))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (rl *RateLimiter) counters(c *gin.Context, route string, policy RoutePolicy) []counter {
+	var counters []counter
+	add := func(limit Limit, scope, id string) {
+		if limit.Requests > 0 {
+			counters = append(counters, counter{
+				key:   "ratelimit:" + route + ":" + scope + ":" + id,
+				limit: limit,
+			})
+		}
+	}
+
+	add(policy.IP, "ip", c.ClientIP())
+	if principal, ok := auth.FromContext(c); ok {
+		if principal.APIKeyID != 0 {
+			add(policy.APIKey, "key", strconv.Itoa(principal.APIKeyID))
+		} else {
+			add(policy.User, "user", strconv.Itoa(principal.UserID))
+		}
+	}
+	return counters
+}
+
+// requestMember is a unique sorted-set member, so requests arriving in the
+// same millisecond are counted separately
+func requestMember() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
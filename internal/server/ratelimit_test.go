@@ -0,0 +1,48 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	got, err := ParseRateLimits("*=ip:300/1m,user:300/1m,key:1200/1m; POST /reward = ip:60/1m, user:30/1m;;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]RoutePolicy{
+		"*": {
+			IP:     Limit{Requests: 300, Window: time.Minute},
+			User:   Limit{Requests: 300, Window: time.Minute},
+			APIKey: Limit{Requests: 1200, Window: time.Minute},
+		},
+		"POST /reward": {
+			IP:   Limit{Requests: 60, Window: time.Minute},
+			User: Limit{Requests: 30, Window: time.Minute},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	if got, err := ParseRateLimits(""); err != nil || len(got) != 0 {
+		t.Errorf("empty spec = %v, %v", got, err)
+	}
+}
+
+func TestParseRateLimitsRejectsBadSpecs(t *testing.T) {
+	for _, spec := range []string{
+		"POST /reward",      // no limits
+		"*=300/1m",          // no scope
+		"*=device:300/1m",   // unknown scope
+		"*=ip:300",          // no window
+		"*=ip:0/1m",         // zero requests
+		"*=ip:300/1 minute", // unparseable window
+		"*=ip:300/10us",     // below the 1ms resolution of the script
+	} {
+		if got, err := ParseRateLimits(spec); err == nil {
+			t.Errorf("ParseRateLimits(%q) = %v, want error", spec, got)
+		}
+	}
+}
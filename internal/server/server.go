@@ -1,12 +1,30 @@
 package server
 
 import (
+	"context"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/angad363/stocky-assignment/internal/accounting"
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/angad363/stocky-assignment/internal/auth"
+	"github.com/angad363/stocky-assignment/internal/broker"
+	"github.com/angad363/stocky-assignment/internal/calendar"
+	"github.com/angad363/stocky-assignment/internal/config"
+	"github.com/angad363/stocky-assignment/internal/contractnote"
+	"github.com/angad363/stocky-assignment/internal/events"
+	"github.com/angad363/stocky-assignment/internal/instruments"
+	"github.com/angad363/stocky-assignment/internal/notifications"
 	"github.com/angad363/stocky-assignment/internal/price"
+	"github.com/angad363/stocky-assignment/internal/reconciliation"
 	referral "github.com/angad363/stocky-assignment/internal/referrals"
+	"github.com/angad363/stocky-assignment/internal/reports"
 	"github.com/angad363/stocky-assignment/internal/reward"
+	"github.com/angad363/stocky-assignment/internal/statement"
+	"github.com/angad363/stocky-assignment/internal/tax"
 	"github.com/angad363/stocky-assignment/internal/users"
+	"github.com/angad363/stocky-assignment/internal/webhooks"
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
@@ -17,10 +35,22 @@ type Server struct {
 	logger *logrus.Logger
 }
 
-func NewServer(logger *logrus.Logger, conn *sqlx.DB) *Server {
+func NewServer(logger *logrus.Logger, conn *sqlx.DB, cfg *config.Config) *Server {
 	r := gin.New()
 
-	r.Use(gin.Recovery())
+	// Client IPs key rate limits and audit entries, so forwarding headers
+	// are only believed from configured proxies
+	var proxies []string
+	for _, p := range strings.Split(cfg.TrustedProxies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		logger.WithError(err).Fatal("Invalid trusted proxy configuration")
+	}
+
+	r.Use(gin.Recovery(), requestID())
 
 	// Global request logging middleware
 	r.Use(func(c *gin.Context) {
@@ -34,25 +64,120 @@ func NewServer(logger *logrus.Logger, conn *sqlx.DB) *Server {
 			"path":     c.Request.URL.Path,
 			"latency":  duration.String(),
 			"clientIP": c.ClientIP(),
+			"request":  c.GetString("request_id"),
 		}).Info("HTTP request processed")
 	})
 
+	marketCalendar, err := calendar.NewCalendar(conn, cfg.MarketOpen, cfg.MarketClose)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid market session configuration")
+	}
+	if err := marketCalendar.Refresh(context.Background()); err != nil {
+		logger.WithError(err).Warn("Failed to load market holidays")
+	}
+	calendarHandler := calendar.NewCalendarHandler(marketCalendar)
+
 	logger.Info("🔧 Initializing Redis and Price services...")
 
 	price.InitRedis()
-	priceService := price.NewPriceService(price.RedisConn)
+	priceService := price.NewPriceService(price.RedisConn, marketCalendar)
 	priceHandler := price.NewPriceHandler(priceService)
 
 	price.StartPriceUpdater(priceService, conn)
 	logger.Info("💹 Price updater started")
 
-	idemService := reward.NewIdempotencyService(price.RedisConn)
-	rewardService := reward.NewRewardService(conn, priceService)
-	rewardHandler := reward.NewRewardHandler(rewardService, idemService)
+	instrumentService := instruments.NewInstrumentService(conn)
+	instrumentHandler := instruments.NewInstrumentHandler(instrumentService)
+
+	idemService := reward.NewIdempotencyService(conn)
+	rewardService := reward.NewRewardService(conn, priceService, instrumentService, marketCalendar,
+		time.Duration(cfg.KYCHoldDays)*24*time.Hour)
+	if n, err := reward.BackfillJournals(context.Background(), conn); err != nil {
+		logger.WithError(err).Fatal("Failed to post journals for existing ledger entries")
+	} else if n > 0 {
+		logger.WithField("entries", n).Info("Posted journals for existing ledger entries")
+	}
+	reward.StartKYCExpiry(rewardService, cfg.KYCExpiryInterval)
+	approvalService := reward.NewApprovalService(conn, rewardService, priceService, instrumentService, cfg.RewardApprovalThreshold)
+	rewardHandler := reward.NewRewardHandler(rewardService, idemService, approvalService)
+
+	simBroker := broker.NewSimulatedBroker(conn, priceService, cfg.BrokerSlippageBps, cfg.BrokerPartialFillRate)
+	orderService := broker.NewOrderService(conn, simBroker, marketCalendar)
+	orderHandler := broker.NewOrderHandler(orderService)
+	broker.StartOrderBatcher(orderService, cfg.BrokerBatchInterval)
+
+	webhookService := webhooks.NewWebhookService(conn)
+	webhookHandler := webhooks.NewWebhookHandler(webhookService)
+	dispatcher := webhooks.NewDispatcher(conn, &http.Client{Timeout: cfg.WebhookTimeout},
+		cfg.WebhookMaxAttempts, cfg.WebhookBackoffBase)
+	webhooks.StartDispatcher(dispatcher, cfg.WebhookPollInterval)
+
+	notificationService := notifications.NewNotificationService(conn)
+	notificationHandler := notifications.NewNotificationHandler(notificationService)
+
+	var sinks []events.Sink
+	for _, name := range strings.Split(cfg.OutboxSinks, ",") {
+		switch strings.TrimSpace(name) {
+		case "log":
+			sinks = append(sinks, events.NewLogSink())
+		case "redis":
+			sinks = append(sinks, events.NewRedisStreamSink(price.RedisConn, cfg.OutboxStream))
+		case "webhook":
+			sinks = append(sinks, webhooks.NewSink(webhookService))
+		case "notifications":
+			sinks = append(sinks, notifications.NewSink(notificationService))
+		case "":
+		default:
+			logger.WithField("sink", name).Warn("Unknown outbox sink ignored")
+		}
+	}
+	events.StartRelay(events.NewRelay(conn, sinks, 100, cfg.OutboxMaxAttempts), cfg.OutboxPollInterval)
+
+	contractNoteService := contractnote.NewContractNoteService(conn)
+	contractNoteHandler := contractnote.NewContractNoteHandler(contractNoteService)
+
+	keyRing, ephemeral, err := auth.ParseKeyRing(cfg.JWTKeys, cfg.JWTActiveKey)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid JWT key configuration")
+	}
+	if ephemeral {
+		logger.Warn("JWT_KEYS not set, using an ephemeral signing key; tokens will not survive a restart")
+	}
+	authService := auth.NewAuthService(conn, keyRing, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	authHandler := auth.NewAuthHandler(authService)
+	if err := authService.BootstrapAdmins(context.Background(), cfg.AdminUserIDs); err != nil {
+		logger.WithError(err).Fatal("Failed to grant bootstrap admin roles")
+	}
+
+	// Callers are identified globally so limits can be keyed by user and API key
+	ratePolicies, err := ParseRateLimits(cfg.RateLimits)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid RATE_LIMITS configuration")
+	}
+	r.Use(authService.Identify(), auditActor(), NewRateLimiter(price.RedisConn, ratePolicies, logger).Middleware())
+
+	auditHandler := audit.NewAuditHandler(audit.NewAuditService(conn))
 
 	userService := users.NewUserService(conn, rewardService)
 	userHandler := users.NewUserHandler(userService)
 
+	statementHandler := statement.NewStatementHandler(statement.NewStatementService(conn, priceService, marketCalendar))
+	taxHandler := tax.NewTaxHandler(tax.NewTaxService(conn))
+	reportHandler := reports.NewReportHandler(reports.NewReportService(conn))
+	exportMap, err := accounting.ParseAccountMap(cfg.AccountingExportMap)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid ACCOUNTING_EXPORT_MAP configuration")
+	}
+	accountingHandler := accounting.NewAccountingHandler(accounting.NewAccountingService(conn), exportMap, cfg.TallyCompany)
+
+	reconciliationAt, err := time.Parse("15:04", cfg.ReconciliationAt)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid RECONCILIATION_AT configuration")
+	}
+	reconciliationService := reconciliation.NewReconciliationService(conn)
+	reconciliationHandler := reconciliation.NewReconciliationHandler(reconciliationService)
+	reconciliation.StartNightly(reconciliationService, reconciliationAt)
+
 	referralService := referral.NewReferralService(conn, rewardService)
 	referralHandler := referral.NewReferralHandler(referralService)
 
@@ -61,7 +186,7 @@ func NewServer(logger *logrus.Logger, conn *sqlx.DB) *Server {
 		logger: logger,
 	}
 
-	s.registerRoutes(priceHandler, rewardHandler, userHandler, referralHandler)
+	s.registerRoutes(priceHandler, rewardHandler, userHandler, referralHandler, instrumentHandler, calendarHandler, orderHandler, contractNoteHandler, webhookHandler, notificationHandler, authService, authHandler, auditHandler, statementHandler, taxHandler, reportHandler, accountingHandler, reconciliationHandler)
 
 	logger.Info("✅ Routes registered successfully")
 
@@ -72,6 +197,20 @@ func (s *Server) registerRoutes(priceHandler *price.PriceHandler,
 	rewardHandler *reward.RewardHandler,
 	userHandler *users.UserHandler,
 	referralHandler *referral.ReferralHandler,
+	instrumentHandler *instruments.InstrumentHandler,
+	calendarHandler *calendar.CalendarHandler,
+	orderHandler *broker.OrderHandler,
+	contractNoteHandler *contractnote.ContractNoteHandler,
+	webhookHandler *webhooks.WebhookHandler,
+	notificationHandler *notifications.NotificationHandler,
+	authService *auth.AuthService,
+	authHandler *auth.AuthHandler,
+	auditHandler *audit.AuditHandler,
+	statementHandler *statement.StatementHandler,
+	taxHandler *tax.TaxHandler,
+	reportHandler *reports.ReportHandler,
+	accountingHandler *accounting.AccountingHandler,
+	reconciliationHandler *reconciliation.ReconciliationHandler,
 ) {
 	s.logger.Info("🛣 Registering routes...")
 
@@ -80,14 +219,93 @@ func (s *Server) registerRoutes(priceHandler *price.PriceHandler,
 		s.logger.Debug("Health check endpoint called")
 	})
 
+	// Role sets shared by the staff and partner routes below
+	adminOnly := auth.RequireRole(auth.RoleAdmin)
+	finance := auth.RequireRole(auth.RoleAdmin, auth.RoleFinance)
+	staff := auth.RequireRole(auth.RoleAdmin, auth.RoleFinance, auth.RoleSupport)
+	issuers := auth.RequireRole(auth.RoleAdmin, auth.RolePartner)
+	reviewers := auth.RequireRole(auth.RoleAdmin, auth.RoleSupport)
+
 	s.router.GET("/price", priceHandler.GetPrice)
-	s.router.POST("/reward", rewardHandler.CreateReward)
 	s.router.POST("/register", userHandler.Register)
-	s.router.GET("/today-stocks/:userId", rewardHandler.GetTodayRewards)
-	s.router.GET("/historical-inr/:userId", rewardHandler.GetHistoricalINR)
-	s.router.GET("/stats/:userId", rewardHandler.GetUserStats)
-	s.router.POST("/refer", referralHandler.CreateReferral)
-	s.router.GET("/portfolio/:userId", rewardHandler.GetUserPortfolio)
+	s.router.GET("/market/status", calendarHandler.GetMarketStatus)
+	s.router.POST("/auth/login", authHandler.Login)
+	s.router.POST("/auth/refresh", authHandler.Refresh)
+	s.router.POST("/auth/logout", authHandler.Logout)
+
+	// User-scoped routes: callers may only read their own data
+	authed := s.router.Group("", authService.RequireAuth())
+	authed.GET("/today-stocks/:userId", auth.RequireSelf("userId"), rewardHandler.GetTodayRewards)
+	authed.GET("/historical-inr/:userId", auth.RequireSelf("userId"), rewardHandler.GetHistoricalINR)
+	authed.GET("/stats/:userId", auth.RequireSelf("userId"), rewardHandler.GetUserStats)
+	authed.GET("/portfolio/:userId", auth.RequireSelf("userId"), rewardHandler.GetUserPortfolio)
+	authed.GET("/rewards", rewardHandler.ListRewards)
+	authed.GET("/rewards/:id/history", rewardHandler.GetRewardHistory)
+	authed.POST("/refer", referralHandler.CreateReferral)
+	authed.POST("/reward", issuers, rewardHandler.CreateReward)
+
+	s.router.GET("/users", authService.RequireAuth(), staff, userHandler.ListUsers)
+	userRoutes := s.router.Group("/users", authService.RequireAuth(), auth.RequireSelf("id"))
+	userRoutes.GET("/:id", userHandler.GetUser)
+	userRoutes.PATCH("/:id", userHandler.UpdateUser)
+	userRoutes.POST("/:id/deactivate", adminOnly, userHandler.DeactivateUser)
+	userRoutes.POST("/:id/reactivate", adminOnly, userHandler.ReactivateUser)
+	userRoutes.POST("/:id/kyc/submit", userHandler.SubmitKYC)
+	userRoutes.GET("/:id/statement", statementHandler.GetStatement)
+	userRoutes.GET("/:id/tax/capital-gains", taxHandler.GetCapitalGains)
+	userRoutes.GET("/:id/tax/perquisites", taxHandler.GetUserPerquisites)
+	userRoutes.GET("/:id/notifications", notificationHandler.GetNotifications)
+	userRoutes.POST("/:id/notifications/read-all", notificationHandler.MarkAllRead)
+	userRoutes.POST("/:id/notifications/:notificationId/read", notificationHandler.MarkRead)
+	userRoutes.GET("/:id/notification-preferences", notificationHandler.GetPreferences)
+	userRoutes.PUT("/:id/notification-preferences", notificationHandler.UpdatePreferences)
+
+	admin := s.router.Group("/admin", authService.RequireAuth())
+	admin.GET("/instruments", staff, instrumentHandler.ListInstruments)
+	admin.GET("/instruments/:symbol", staff, instrumentHandler.GetInstrument)
+	admin.POST("/instruments", adminOnly, instrumentHandler.CreateInstrument)
+	admin.PUT("/instruments/:symbol", adminOnly, instrumentHandler.UpdateInstrument)
+	admin.DELETE("/instruments/:symbol", adminOnly, instrumentHandler.DeleteInstrument)
+	admin.POST("/rewards/:id/status", adminOnly, rewardHandler.TransitionReward)
+	admin.GET("/reward-approvals", finance, rewardHandler.ListApprovals)
+	admin.GET("/reward-approvals/:id", finance, rewardHandler.GetApproval)
+	admin.POST("/reward-approvals/:id/approve", adminOnly, rewardHandler.ApproveReward)
+	admin.POST("/reward-approvals/:id/reject", adminOnly, rewardHandler.RejectReward)
+	admin.POST("/broker/batches", adminOnly, orderHandler.RunBatch)
+	admin.GET("/broker/orders", finance, orderHandler.ListOrders)
+	admin.GET("/broker/orders/:id", finance, orderHandler.GetOrder)
+	admin.POST("/contract-notes", finance, contractNoteHandler.UploadContractNote)
+	admin.GET("/contract-notes", finance, contractNoteHandler.ListContractNotes)
+	admin.GET("/contract-notes/:id/reconciliation", finance, contractNoteHandler.GetReconciliation)
+	admin.POST("/fee-differences/:id/approve", finance, contractNoteHandler.ApproveDifference)
+	admin.POST("/fee-differences/:id/reject", finance, contractNoteHandler.RejectDifference)
+	admin.POST("/webhooks", adminOnly, webhookHandler.CreateSubscription)
+	admin.GET("/webhooks", adminOnly, webhookHandler.ListSubscriptions)
+	admin.GET("/webhooks/:id", adminOnly, webhookHandler.GetSubscription)
+	admin.PUT("/webhooks/:id", adminOnly, webhookHandler.UpdateSubscription)
+	admin.DELETE("/webhooks/:id", adminOnly, webhookHandler.DeleteSubscription)
+	admin.GET("/webhooks/:id/deliveries", adminOnly, webhookHandler.ListDeliveries)
+	admin.POST("/webhook-deliveries/:id/replay", adminOnly, webhookHandler.ReplayDelivery)
+	admin.GET("/calendar/holidays", staff, calendarHandler.ListHolidays)
+	admin.POST("/calendar/holidays", adminOnly, calendarHandler.LoadHolidays)
+	admin.DELETE("/calendar/holidays/:date", adminOnly, calendarHandler.DeleteHoliday)
+	admin.GET("/users/:id/roles", adminOnly, authHandler.GetUserRoles)
+	admin.POST("/users/:id/kyc", reviewers, userHandler.SetKYCStatus)
+	admin.PUT("/users/:id/roles", adminOnly, authHandler.SetUserRoles)
+	admin.POST("/api-keys", adminOnly, authHandler.CreateAPIKey)
+	admin.GET("/api-keys", adminOnly, authHandler.ListAPIKeys)
+	admin.DELETE("/api-keys/:id", adminOnly, authHandler.RevokeAPIKey)
+	admin.GET("/audit", finance, auditHandler.ListAudit)
+	admin.GET("/audit/verify", finance, auditHandler.VerifyAudit)
+	admin.GET("/reports/perquisites", finance, taxHandler.GetPerquisites)
+	admin.GET("/reports/expenses", finance, reportHandler.GetExpenses)
+	admin.GET("/accounting/check", finance, accountingHandler.CheckLedger)
+	admin.GET("/accounting/trial-balance", finance, accountingHandler.GetTrialBalance)
+	admin.GET("/accounting/balance-sheet", finance, accountingHandler.GetBalanceSheet)
+	admin.GET("/accounting/export", finance, accountingHandler.Export)
+	admin.POST("/reconciliation/runs", finance, reconciliationHandler.StartRun)
+	admin.GET("/reconciliation/runs", finance, reconciliationHandler.ListRuns)
+	admin.GET("/reconciliation/runs/:id", finance, reconciliationHandler.GetRun)
 
 	s.logger.Info("📡 All API routes registered")
 }
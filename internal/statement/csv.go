@@ -0,0 +1,49 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+)
+
+var csvHeader = []string{"section", "date", "type", "reward_id", "symbol", "campaign",
+	"quantity", "price", "price_as_of", "value", "note"}
+
+// WriteCSV writes the statement as one table; the section column tells
+// opening holdings, transactions and closing holdings apart
+func WriteCSV(w io.Writer, st Statement) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, h := range st.Opening {
+		writer.Write([]string{"opening", st.From, "", "", h.Symbol, "", formatQty(h.Quantity), "", "", "", ""})
+	}
+	for _, t := range st.Transactions {
+		writer.Write([]string{"transaction", t.OccurredAt.In(calendar.IST).Format("2006-01-02 15:04:05"), t.Type,
+			strconv.Itoa(t.RewardID), t.Symbol, t.Campaign, formatQty(t.Quantity), "", "", "", t.Note})
+	}
+	for _, h := range st.Closing {
+		asOf := ""
+		if h.PriceAsOf != nil {
+			asOf = h.PriceAsOf.In(calendar.IST).Format("2006-01-02")
+		}
+		writer.Write([]string{"closing", st.To, "", "", h.Symbol, "", formatQty(h.Quantity),
+			formatINR(h.Price), asOf, formatINR(h.Value), ""})
+	}
+	writer.Write([]string{"total", st.To, "", "", "", "", "", "", "", formatINR(st.ClosingValue), ""})
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatQty(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
+
+func formatINR(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
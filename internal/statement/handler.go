@@ -0,0 +1,85 @@
+package statement
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type StatementHandler struct {
+	service *StatementService
+}
+
+func NewStatementHandler(service *StatementService) *StatementHandler {
+	return &StatementHandler{service: service}
+}
+
+// GetStatement handles GET /users/:id/statement?from=YYYY-MM-DD&to=YYYY-MM-DD&format=pdf|csv.
+// The period defaults to the current month to date.
+func (h *StatementHandler) GetStatement(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	today := time.Now().In(calendar.IST)
+	from := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, calendar.IST)
+	to := today
+	for param, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		if v := c.Query(param); v != "" {
+			day, err := time.ParseInLocation("2006-01-02", v, calendar.IST)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + ", use YYYY-MM-DD"})
+				return
+			}
+			*dst = day
+		}
+	}
+
+	format := c.DefaultQuery("format", "pdf")
+	if format != "pdf" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be pdf or csv"})
+		return
+	}
+
+	st, err := h.service.Build(c.Request.Context(), userID, from, to)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrInvalidPeriod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Log.Errorf("Failed to build statement for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build statement"})
+		return
+	}
+
+	// Render fully before writing so a failure still returns a JSON error
+	var buf bytes.Buffer
+	contentType := "application/pdf"
+	if format == "csv" {
+		contentType = "text/csv"
+		err = WriteCSV(&buf, st)
+	} else {
+		err = WritePDF(&buf, st)
+	}
+	if err != nil {
+		logger.Log.Errorf("Failed to render %s statement for user %d: %v", format, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render statement"})
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%d-%s-%s.%s", userID, st.From, st.To, format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
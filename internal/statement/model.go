@@ -0,0 +1,52 @@
+package statement
+
+import "time"
+
+// Statement is a user's holdings over a period: what they held at the
+// start, every movement in between and what they held at the end
+type Statement struct {
+	User         Account       `json:"user"`
+	From         string        `json:"from"` // IST dates, both inclusive
+	To           string        `json:"to"`
+	GeneratedAt  time.Time     `json:"generated_at"`
+	Opening      []Holding     `json:"opening"`
+	Transactions []Transaction `json:"transactions"`
+	Closing      []Holding     `json:"closing"`
+	ClosingValue float64       `json:"closing_value"`
+}
+
+type Account struct {
+	ID             int    `db:"id" json:"id"`
+	Name           string `db:"name" json:"name"`
+	PAN            string `db:"pan" json:"pan"`
+	DematAccountID string `db:"demat_account_id" json:"demat_account_id"`
+}
+
+// Holding is a position at one end of the period. Closing holdings are
+// valued at the last session close on or before the period end.
+type Holding struct {
+	Symbol    string     `db:"symbol" json:"symbol"`
+	Quantity  float64    `db:"quantity" json:"quantity"`
+	Price     float64    `json:"price,omitempty"`
+	PriceAsOf *time.Time `json:"price_as_of,omitempty"`
+	Value     float64    `json:"value,omitempty"`
+}
+
+// Transaction types. Quantities are signed: rewards and retries add
+// shares, reversals and failed purchases remove them.
+const (
+	TypeReward   = "reward"
+	TypeReversal = "reversal"
+	TypeFailed   = "failed"
+	TypeRetry    = "retry"
+)
+
+type Transaction struct {
+	OccurredAt time.Time `db:"occurred_at" json:"occurred_at"`
+	Type       string    `db:"type" json:"type"`
+	RewardID   int       `db:"reward_id" json:"reward_id"`
+	Symbol     string    `db:"symbol" json:"symbol"`
+	Quantity   float64   `db:"quantity" json:"quantity"`
+	Campaign   string    `db:"campaign" json:"campaign"`
+	Note       string    `db:"note" json:"note"`
+}
@@ -0,0 +1,194 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+)
+
+// The PDF is written by hand rather than with a library: statements are
+// plain text tables, which only need the standard Courier fonts that every
+// viewer ships with, so nothing has to be embedded.
+const (
+	pageWidth    = 595 // A4 in points
+	pageHeight   = 842
+	margin       = 40
+	fontSize     = 9
+	leading      = 12
+	linesPerPage = (pageHeight - 2*margin - 2*leading) / leading
+)
+
+type pdfLine struct {
+	text string
+	bold bool
+}
+
+// WritePDF renders the statement as an A4 PDF
+func WritePDF(w io.Writer, st Statement) error {
+	lines := statementLines(st)
+
+	var pages [][]pdfLine
+	for len(lines) > linesPerPage {
+		pages = append(pages, lines[:linesPerPage])
+		lines = lines[linesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	doc := &pdfWriter{}
+	doc.header()
+
+	// Objects 1-4 are fixed; each page then takes a page and a content object
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	doc.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	doc.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	doc.object(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	doc.object(4, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range pages {
+		var content bytes.Buffer
+		y := pageHeight - margin
+		for _, line := range page {
+			writeText(&content, margin, y, line.bold, line.text)
+			y -= leading
+		}
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(pages))
+		writeText(&content, pageWidth-margin-len(footer)*fontSize*6/10, margin, false, footer)
+
+		pageID := 5 + 2*i
+		doc.object(pageID, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, pageID+1))
+		doc.object(pageID+1, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.Bytes()))
+	}
+
+	doc.trailer()
+	_, err := w.Write(doc.buf.Bytes())
+	return err
+}
+
+// statementLines lays the statement out as fixed-width text
+func statementLines(st Statement) []pdfLine {
+	var lines []pdfLine
+	add := func(bold bool, format string, args ...interface{}) {
+		lines = append(lines, pdfLine{text: fmt.Sprintf(format, args...), bold: bold})
+	}
+
+	add(true, "Stocky - Statement of Holdings")
+	add(false, "")
+	add(false, "Account:   %d  %s", st.User.ID, st.User.Name)
+	if st.User.PAN != "" {
+		add(false, "PAN:       %s", st.User.PAN)
+	}
+	if st.User.DematAccountID != "" {
+		add(false, "Demat A/c: %s", st.User.DematAccountID)
+	}
+	add(false, "Period:    %s to %s", st.From, st.To)
+	add(false, "Generated: %s IST", st.GeneratedAt.In(calendar.IST).Format("2006-01-02 15:04"))
+	add(false, "")
+
+	add(true, "Opening holdings as of %s", st.From)
+	add(true, "%-20s %16s", "Symbol", "Quantity")
+	for _, h := range st.Opening {
+		add(false, "%-20s %16s", h.Symbol, formatQty(h.Quantity))
+	}
+	if len(st.Opening) == 0 {
+		add(false, "No holdings")
+	}
+	add(false, "")
+
+	add(true, "Transactions")
+	add(true, "%-16s %-9s %8s %-12s %-12s %14s", "Date (IST)", "Type", "Reward", "Symbol", "Campaign", "Quantity")
+	for _, t := range st.Transactions {
+		add(false, "%-16s %-9s %8d %-12s %-12s %14s",
+			t.OccurredAt.In(calendar.IST).Format("2006-01-02 15:04"), t.Type, t.RewardID,
+			truncate(t.Symbol, 12), truncate(t.Campaign, 12), formatQty(t.Quantity))
+	}
+	if len(st.Transactions) == 0 {
+		add(false, "No transactions in this period")
+	}
+	add(false, "")
+
+	add(true, "Closing holdings as of %s", st.To)
+	add(true, "%-12s %14s %12s %-11s %16s", "Symbol", "Quantity", "Price", "Price date", "Value (INR)")
+	for _, h := range st.Closing {
+		asOf := ""
+		if h.PriceAsOf != nil {
+			asOf = h.PriceAsOf.In(calendar.IST).Format("2006-01-02")
+		}
+		add(false, "%-12s %14s %12s %-11s %16s",
+			truncate(h.Symbol, 12), formatQty(h.Quantity), formatINR(h.Price), asOf, formatINR(h.Value))
+	}
+	if len(st.Closing) == 0 {
+		add(false, "No holdings")
+	}
+	add(true, "%-12s %14s %12s %-11s %16s", "Total", "", "", "", formatINR(st.ClosingValue))
+	add(false, "")
+	add(false, "Holdings include rewards still being bought or settled and rewards held")
+	add(false, "pending KYC. Failed and reversed rewards are excluded.")
+
+	return lines
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+func writeText(buf *bytes.Buffer, x, y int, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(buf, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, fontSize, x, y, escapePDF(text))
+}
+
+// escapePDF quotes a literal string. Characters outside printable ASCII
+// are replaced since the standard fonts only cover WinAnsi.
+func escapePDF(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// pdfWriter tracks object offsets for the cross-reference table
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (p *pdfWriter) header() {
+	p.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+}
+
+// object writes objects in ID order starting at 1
+func (p *pdfWriter) object(id int, body string) {
+	p.offsets = append(p.offsets, p.buf.Len())
+	fmt.Fprintf(&p.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (p *pdfWriter) trailer() {
+	xref := p.buf.Len()
+	fmt.Fprintf(&p.buf, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, off := range p.offsets {
+		fmt.Fprintf(&p.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&p.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, xref)
+}
@@ -0,0 +1,134 @@
+package statement
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+	"github.com/angad363/stocky-assignment/internal/price"
+	"github.com/angad363/stocky-assignment/internal/reward"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrUserNotFound  = reward.ErrUserNotFound
+	ErrInvalidPeriod = errors.New("from must not be after to")
+)
+
+type StatementService struct {
+	db       *sqlx.DB
+	priceSvc *price.PriceService
+	calendar *calendar.Calendar
+}
+
+func NewStatementService(db *sqlx.DB, priceSvc *price.PriceService, cal *calendar.Calendar) *StatementService {
+	return &StatementService{db: db, priceSvc: priceSvc, calendar: cal}
+}
+
+// Build assembles the statement for the IST calendar days from..to
+// inclusive. Failed and reversed rewards do not count as holdings, so
+// opening holdings plus the period's transactions equal closing holdings.
+func (s *StatementService) Build(ctx context.Context, userID int, from, to time.Time) (Statement, error) {
+	var st Statement
+
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, calendar.IST)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, calendar.IST)
+	if from.After(to) {
+		return st, ErrInvalidPeriod
+	}
+	end := to.AddDate(0, 0, 1)
+
+	err := s.db.GetContext(ctx, &st.User, `
+		SELECT id, name, pan, demat_account_id FROM users WHERE id = $1
+	`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return st, ErrUserNotFound
+	}
+	if err != nil {
+		return st, err
+	}
+
+	st.From = from.Format("2006-01-02")
+	st.To = to.Format("2006-01-02")
+	st.GeneratedAt = time.Now()
+
+	if st.Opening, err = s.holdingsAt(ctx, userID, from); err != nil {
+		return st, err
+	}
+	if st.Closing, err = s.holdingsAt(ctx, userID, end); err != nil {
+		return st, err
+	}
+
+	st.Transactions = []Transaction{}
+	err = s.db.SelectContext(ctx, &st.Transactions, `
+		SELECT rewarded_at AS occurred_at, 'reward' AS type, id AS reward_id,
+		       stock_symbol AS symbol, quantity, campaign, '' AS note
+		FROM rewards
+		WHERE user_id = $1 AND rewarded_at >= $2 AND rewarded_at < $3
+		UNION ALL
+		SELECT e.created_at,
+		       CASE e.to_status WHEN 'reversed' THEN 'reversal' WHEN 'failed' THEN 'failed' ELSE 'retry' END,
+		       r.id, r.stock_symbol,
+		       CASE WHEN e.to_status IN ('failed', 'reversed') THEN -r.quantity ELSE r.quantity END,
+		       r.campaign, e.note
+		FROM reward_events e
+		JOIN rewards r ON r.id = e.reward_id
+		WHERE r.user_id = $1 AND e.created_at >= $2 AND e.created_at < $3
+		  AND (e.to_status IN ('failed', 'reversed') OR e.from_status = 'failed')
+		ORDER BY occurred_at, reward_id
+	`, userID, from, end)
+	if err != nil {
+		return st, err
+	}
+
+	// Value closing holdings at the close of the last session in the period
+	session := s.calendar.SessionDate(to)
+	for i := range st.Closing {
+		h := &st.Closing[i]
+		quote, ok, err := price.ClosingPrice(ctx, s.db, h.Symbol, session)
+		if err != nil {
+			return st, err
+		}
+		if !ok {
+			// No close recorded for the period yet, e.g. a period ending today
+			if quote, err = s.priceSvc.GetStockPrice(h.Symbol); err != nil {
+				return st, err
+			}
+		}
+		asOf := quote.AsOf
+		h.Price = quote.Price
+		h.PriceAsOf = &asOf
+		h.Value = math.Round(h.Quantity*quote.Price*100) / 100
+		st.ClosingValue += h.Value
+	}
+	st.ClosingValue = math.Round(st.ClosingValue*100) / 100
+
+	return st, nil
+}
+
+// holdingsAt sums the rewards granted before at whose status at that
+// moment was neither failed nor reversed
+func (s *StatementService) holdingsAt(ctx context.Context, userID int, at time.Time) ([]Holding, error) {
+	holdings := []Holding{}
+	err := s.db.SelectContext(ctx, &holdings, `
+		SELECT r.stock_symbol AS symbol, SUM(r.quantity) AS quantity
+		FROM rewards r
+		LEFT JOIN LATERAL (
+			SELECT e.to_status
+			FROM reward_events e
+			WHERE e.reward_id = r.id AND e.created_at < $2
+			ORDER BY e.created_at DESC, e.id DESC
+			LIMIT 1
+		) last ON TRUE
+		WHERE r.user_id = $1
+		  AND r.rewarded_at < $2
+		  AND COALESCE(last.to_status, 'pending') NOT IN ('failed', 'reversed')
+		GROUP BY r.stock_symbol
+		HAVING SUM(r.quantity) <> 0
+		ORDER BY r.stock_symbol
+	`, userID, at)
+	return holdings, err
+}
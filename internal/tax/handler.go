@@ -0,0 +1,105 @@
+package tax
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type TaxHandler struct {
+	service *TaxService
+}
+
+func NewTaxHandler(service *TaxService) *TaxHandler {
+	return &TaxHandler{service: service}
+}
+
+// GetCapitalGains handles GET /users/:id/tax/capital-gains?fy=2025-26
+// (defaults to the current financial year)
+func (h *TaxHandler) GetCapitalGains(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	fy := c.DefaultQuery("fy", CurrentFinancialYear(time.Now()))
+
+	report, err := h.service.CapitalGains(c.Request.Context(), userID, fy)
+	switch {
+	case errors.Is(err, ErrInvalidFinancialYear):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Log.Errorf("Failed to compute capital gains for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute capital gains"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetUserPerquisites handles GET /users/:id/tax/perquisites?fy=2025-26&format=json|csv
+func (h *TaxHandler) GetUserPerquisites(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	h.perquisites(c, &userID)
+}
+
+// GetPerquisites handles GET /admin/reports/perquisites?fy=2025-26&format=json|csv,
+// the company-wide report used for TDS
+func (h *TaxHandler) GetPerquisites(c *gin.Context) {
+	h.perquisites(c, nil)
+}
+
+func (h *TaxHandler) perquisites(c *gin.Context, userID *int) {
+	fy := c.DefaultQuery("fy", CurrentFinancialYear(time.Now()))
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	report, err := h.service.Perquisites(c.Request.Context(), userID, fy)
+	switch {
+	case errors.Is(err, ErrInvalidFinancialYear):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Log.Errorf("Failed to build perquisite report for %s: %v", fy, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build perquisite report"})
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := WritePerquisitesCSV(&buf, report); err != nil {
+		logger.Log.Errorf("Failed to write perquisite report for %s: %v", fy, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build perquisite report"})
+		return
+	}
+	filename := "perquisites-" + fy + ".csv"
+	if userID != nil {
+		filename = fmt.Sprintf("perquisites-%d-%s.csv", *userID, fy)
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "text/csv", buf.Bytes())
+}
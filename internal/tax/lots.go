@@ -0,0 +1,99 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrInsufficientLots = errors.New("not enough open tax lots")
+
+// OpenLot records the lot for a newly granted reward inside the caller's
+// transaction
+func OpenLot(ctx context.Context, tx *sqlx.Tx, lot Lot) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO tax_lots (reward_id, user_id, stock_symbol, acquired_at, quantity, remaining, cost_per_share)
+		VALUES ($1, $2, $3, $4, $5, $5, $6)
+	`, lot.RewardID, lot.UserID, lot.StockSymbol, lot.AcquiredAt, lot.Quantity, math.Round(lot.CostPerShare*10000)/10000)
+	return err
+}
+
+// ConsumeLots takes the disposed quantity from the user's open lots of the
+// symbol and records which lots it came from. A sale takes the oldest lots
+// first; a reversal takes back the shares of the reversed reward, so it
+// only ever closes that reward's own lot. Expenses are split across lots in
+// proportion to quantity.
+func ConsumeLots(ctx context.Context, tx *sqlx.Tx, d Disposal) error {
+	query := `
+		SELECT id, reward_id, user_id, stock_symbol, acquired_at, quantity, remaining, cost_per_share
+		FROM tax_lots
+		WHERE user_id = $1 AND stock_symbol = $2 AND remaining > 0`
+	args := []interface{}{d.UserID, d.StockSymbol}
+	if d.Kind == DisposalReversal {
+		if d.RewardID == nil {
+			return errors.New("reversal disposal requires the reversed reward")
+		}
+		query += ` AND reward_id = $3`
+		args = append(args, *d.RewardID)
+	}
+
+	lots := []Lot{}
+	err := tx.SelectContext(ctx, &lots, query+`
+		ORDER BY acquired_at, id
+		FOR UPDATE
+	`, args...)
+	if err != nil {
+		return err
+	}
+
+	takes, err := splitDisposal(lots, d)
+	if err != nil {
+		return err
+	}
+	for _, t := range takes {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE tax_lots SET remaining = remaining - $2 WHERE id = $1
+		`, t.lot.ID, t.quantity); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO lot_disposals (lot_id, kind, reward_id, quantity, sale_price, expenses, disposed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, t.lot.ID, d.Kind, d.RewardID, t.quantity, d.SalePrice, t.expenses, d.DisposedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lotTake is the part of one lot a disposal uses
+type lotTake struct {
+	lot      Lot
+	quantity float64
+	expenses float64
+}
+
+// splitDisposal takes the disposed quantity from lots in the order given
+// and splits the expenses pro rata
+func splitDisposal(lots []Lot, d Disposal) ([]lotTake, error) {
+	var takes []lotTake
+	left := d.Quantity
+	for _, lot := range lots {
+		if left <= 0 {
+			break
+		}
+		take := math.Min(lot.Remaining, left)
+		expenses := math.Round(d.Expenses*take/d.Quantity*100) / 100
+		takes = append(takes, lotTake{lot: lot, quantity: take, expenses: expenses})
+		left -= take
+	}
+
+	// Quantities carry six decimals, so anything below that is rounding
+	if left > 1e-6 {
+		return nil, fmt.Errorf("%w: %s short by %v for user %d", ErrInsufficientLots, d.StockSymbol, left, d.UserID)
+	}
+	return takes, nil
+}
@@ -0,0 +1,77 @@
+package tax
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSplitDisposal(t *testing.T) {
+	lots := []Lot{
+		{ID: 1, Remaining: 2},
+		{ID: 2, Remaining: 1.5},
+		{ID: 3, Remaining: 3},
+	}
+
+	type take struct {
+		lotID    int
+		quantity float64
+		expenses float64
+	}
+	tests := []struct {
+		name string
+		d    Disposal
+		want []take
+		err  error
+	}{
+		{
+			name: "within the oldest lot",
+			d:    Disposal{Kind: DisposalSell, Quantity: 1.5, Expenses: 30},
+			want: []take{{1, 1.5, 30}},
+		},
+		{
+			name: "across lots with expenses pro rata",
+			d:    Disposal{Kind: DisposalSell, Quantity: 4, Expenses: 20},
+			want: []take{{1, 2, 10}, {2, 1.5, 7.5}, {3, 0.5, 2.5}},
+		},
+		{
+			name: "every lot exactly",
+			d:    Disposal{Kind: DisposalSell, Quantity: 6.5},
+			want: []take{{1, 2, 0}, {2, 1.5, 0}, {3, 3, 0}},
+		},
+		{
+			name: "shortfall below quantity precision",
+			d:    Disposal{Kind: DisposalSell, Quantity: 6.5000004},
+			want: []take{{1, 2, 0}, {2, 1.5, 0}, {3, 3, 0}},
+		},
+		{
+			name: "not enough shares",
+			d:    Disposal{Kind: DisposalSell, Quantity: 7},
+			err:  ErrInsufficientLots,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			takes, err := splitDisposal(lots, tt.d)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(takes) != len(tt.want) {
+				t.Fatalf("got %d lots, want %d", len(takes), len(tt.want))
+			}
+			for i, w := range tt.want {
+				got := takes[i]
+				if got.lot.ID != w.lotID || got.quantity != w.quantity || got.expenses != w.expenses {
+					t.Errorf("take %d = lot %d qty %v exp %v, want lot %d qty %v exp %v",
+						i, got.lot.ID, got.quantity, got.expenses, w.lotID, w.quantity, w.expenses)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package tax
+
+import "time"
+
+// Lot is the shares acquired by one reward. The cost basis is the fair
+// market value at grant, which is also what was taxed as a perquisite.
+type Lot struct {
+	ID           int       `db:"id" json:"id"`
+	RewardID     int       `db:"reward_id" json:"reward_id"`
+	UserID       int       `db:"user_id" json:"user_id"`
+	StockSymbol  string    `db:"stock_symbol" json:"stock_symbol"`
+	AcquiredAt   time.Time `db:"acquired_at" json:"acquired_at"`
+	Quantity     float64   `db:"quantity" json:"quantity"`
+	Remaining    float64   `db:"remaining" json:"remaining"`
+	CostPerShare float64   `db:"cost_per_share" json:"cost_per_share"`
+}
+
+// Disposal kinds. Only sales are transfers for capital gains; a reversal
+// takes the shares back without consideration. Nothing records sales yet:
+// users cannot sell reward shares, so DisposalSell is reserved for that
+// flow and capital gains reports are empty until it exists.
+const (
+	DisposalSell     = "sell"
+	DisposalReversal = "reversal"
+)
+
+// Disposal removes shares of a symbol from a user's lots: a sale's oldest
+// first, a reversal's from the reversed reward's lot
+type Disposal struct {
+	Kind        string
+	UserID      int
+	StockSymbol string
+	Quantity    float64
+	SalePrice   float64 // per share; zero for reversals
+	Expenses    float64 // brokerage and other transfer costs for the whole quantity
+	RewardID    *int    // the reversed reward; required for reversals
+	DisposedAt  time.Time
+}
+
+// Holding period classification for listed equity shares
+const (
+	TermShort = "short_term"
+	TermLong  = "long_term"
+)
+
+// LotGain is one lot's share of a sale, as reported in Schedule CG
+type LotGain struct {
+	DisposalID   int       `db:"disposal_id" json:"disposal_id"`
+	LotID        int       `db:"lot_id" json:"lot_id"`
+	RewardID     int       `db:"reward_id" json:"reward_id"`
+	StockSymbol  string    `db:"stock_symbol" json:"stock_symbol"`
+	ISIN         string    `db:"isin" json:"isin"`
+	Quantity     float64   `db:"quantity" json:"quantity"`
+	AcquiredAt   time.Time `db:"acquired_at" json:"acquired_at"`
+	SoldAt       time.Time `db:"sold_at" json:"sold_at"`
+	CostPerShare float64   `db:"cost_per_share" json:"cost_per_share"`
+	SalePrice    float64   `db:"sale_price" json:"sale_price"`
+	CostBasis    float64   `db:"-" json:"cost_basis"`
+	SaleValue    float64   `db:"-" json:"sale_value"`
+	Expenses     float64   `db:"expenses" json:"expenses"`
+	Gain         float64   `db:"-" json:"gain"`
+	HoldingDays  int       `db:"-" json:"holding_days"`
+	Term         string    `db:"-" json:"term"`
+}
+
+type GainsSummary struct {
+	SaleValue float64 `json:"sale_value"`
+	CostBasis float64 `json:"cost_basis"`
+	Expenses  float64 `json:"expenses"`
+	Gain      float64 `json:"gain"`
+}
+
+type CapitalGainsReport struct {
+	UserID        int          `json:"user_id"`
+	FinancialYear string       `json:"financial_year"`
+	From          string       `json:"from"` // IST dates, both inclusive
+	To            string       `json:"to"`
+	ShortTerm     GainsSummary `json:"short_term"`
+	LongTerm      GainsSummary `json:"long_term"`
+	Lots          []LotGain    `json:"lots"`
+}
+
+// PerquisiteRow totals the rewards one user was granted in a month under
+// one campaign, valued at grant-date prices
+type PerquisiteRow struct {
+	UserID        int     `db:"user_id" json:"user_id"`
+	Name          string  `db:"name" json:"name"`
+	PAN           string  `db:"pan" json:"pan"`
+	Month         string  `db:"month" json:"month"` // YYYY-MM in IST
+	Campaign      string  `db:"campaign" json:"campaign"`
+	Rewards       int     `db:"rewards" json:"rewards"`
+	Unpriced      int     `db:"unpriced" json:"unpriced"` // granted before prices were captured
+	GrantValue    float64 `db:"grant_value" json:"grant_value"`
+	ReversedValue float64 `db:"reversed_value" json:"reversed_value"`
+	NetValue      float64 `db:"-" json:"net_value"`
+}
+
+type PerquisiteReport struct {
+	FinancialYear string          `json:"financial_year"`
+	From          string          `json:"from"`
+	To            string          `json:"to"`
+	UserID        *int            `json:"user_id,omitempty"` // nil for the company-wide report
+	Rows          []PerquisiteRow `json:"rows"`
+	GrantValue    float64         `json:"grant_value"`
+	ReversedValue float64         `json:"reversed_value"`
+	NetValue      float64         `json:"net_value"`
+}
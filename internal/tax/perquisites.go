@@ -0,0 +1,88 @@
+package tax
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// Perquisites totals the value at grant of rewards granted in the
+// financial year, by user, IST month and campaign. userID narrows it to
+// one user; nil reports company-wide for TDS. Rewards reversed since are
+// shown separately so finance can see what was clawed back.
+func (s *TaxService) Perquisites(ctx context.Context, userID *int, fy string) (PerquisiteReport, error) {
+	report := PerquisiteReport{FinancialYear: fy, UserID: userID, Rows: []PerquisiteRow{}}
+
+	from, to, err := FinancialYear(fy)
+	if err != nil {
+		return report, err
+	}
+	report.From = from.Format("2006-01-02")
+	report.To = to.AddDate(0, 0, -1).Format("2006-01-02")
+
+	if userID != nil {
+		var exists bool
+		if err := s.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, *userID); err != nil {
+			return report, err
+		}
+		if !exists {
+			return report, ErrUserNotFound
+		}
+	}
+
+	err = s.db.SelectContext(ctx, &report.Rows, `
+		SELECT r.user_id, u.name, u.pan,
+		       to_char(r.rewarded_at AT TIME ZONE 'Asia/Kolkata', 'YYYY-MM') AS month,
+		       r.campaign,
+		       COUNT(*) AS rewards,
+		       COUNT(*) FILTER (WHERE r.grant_price IS NULL) AS unpriced,
+		       ROUND(COALESCE(SUM(r.quantity * r.grant_price), 0), 2) AS grant_value,
+		       ROUND(COALESCE(SUM(r.quantity * r.grant_price) FILTER (WHERE r.status = 'reversed'), 0), 2) AS reversed_value
+		FROM rewards r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.rewarded_at >= $1 AND r.rewarded_at < $2
+		  AND ($3::int IS NULL OR r.user_id = $3)
+		GROUP BY r.user_id, u.name, u.pan, month, r.campaign
+		ORDER BY r.user_id, month, r.campaign
+	`, from, to, userID)
+	if err != nil {
+		return report, err
+	}
+
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		row.NetValue = round2(row.GrantValue - row.ReversedValue)
+		report.GrantValue += row.GrantValue
+		report.ReversedValue += row.ReversedValue
+	}
+	report.GrantValue = round2(report.GrantValue)
+	report.ReversedValue = round2(report.ReversedValue)
+	report.NetValue = round2(report.GrantValue - report.ReversedValue)
+
+	return report, nil
+}
+
+var perquisiteHeader = []string{"user_id", "name", "pan", "month", "campaign", "rewards", "unpriced",
+	"grant_value", "reversed_value", "net_value"}
+
+// WritePerquisitesCSV writes one line per report row
+func WritePerquisitesCSV(w io.Writer, report PerquisiteReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(perquisiteHeader); err != nil {
+		return err
+	}
+	for _, row := range report.Rows {
+		writer.Write([]string{
+			strconv.Itoa(row.UserID), row.Name, row.PAN, row.Month, row.Campaign,
+			strconv.Itoa(row.Rewards), strconv.Itoa(row.Unpriced),
+			formatINR(row.GrantValue), formatINR(row.ReversedValue), formatINR(row.NetValue),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatINR(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
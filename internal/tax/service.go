@@ -0,0 +1,134 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrUserNotFound         = errors.New("user not found")
+	ErrInvalidFinancialYear = errors.New("financial year must look like 2025-26")
+)
+
+// Listed equity shares are long-term when held for more than 12 months
+// before the transfer (section 2(42A))
+const longTermMonths = 12
+
+var fyPattern = regexp.MustCompile(`^(\d{4})-(\d{2})$`)
+
+type TaxService struct {
+	db *sqlx.DB
+}
+
+func NewTaxService(db *sqlx.DB) *TaxService {
+	return &TaxService{db: db}
+}
+
+// FinancialYear parses "2025-26" into its first day and the first day of
+// the next year, both in IST
+func FinancialYear(fy string) (time.Time, time.Time, error) {
+	m := fyPattern.FindStringSubmatch(fy)
+	if m == nil {
+		return time.Time{}, time.Time{}, ErrInvalidFinancialYear
+	}
+	start, _ := strconv.Atoi(m[1])
+	end, _ := strconv.Atoi(m[2])
+	if (start+1)%100 != end {
+		return time.Time{}, time.Time{}, ErrInvalidFinancialYear
+	}
+	from := time.Date(start, time.April, 1, 0, 0, 0, 0, calendar.IST)
+	return from, from.AddDate(1, 0, 0), nil
+}
+
+// CurrentFinancialYear names the financial year containing t
+func CurrentFinancialYear(t time.Time) string {
+	t = t.In(calendar.IST)
+	start := t.Year()
+	if t.Month() < time.April {
+		start--
+	}
+	return fmt.Sprintf("%d-%02d", start, (start+1)%100)
+}
+
+// CapitalGains reports every lot sold by the user in the financial year
+// with its gain, classified by holding period
+func (s *TaxService) CapitalGains(ctx context.Context, userID int, fy string) (CapitalGainsReport, error) {
+	report := CapitalGainsReport{UserID: userID, FinancialYear: fy, Lots: []LotGain{}}
+
+	from, to, err := FinancialYear(fy)
+	if err != nil {
+		return report, err
+	}
+	report.From = from.Format("2006-01-02")
+	report.To = to.AddDate(0, 0, -1).Format("2006-01-02")
+
+	var exists bool
+	if err := s.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID); err != nil {
+		return report, err
+	}
+	if !exists {
+		return report, ErrUserNotFound
+	}
+
+	err = s.db.SelectContext(ctx, &report.Lots, `
+		SELECT d.id AS disposal_id, l.id AS lot_id, l.reward_id, l.stock_symbol,
+		       COALESCE(i.isin, '') AS isin, d.quantity, l.acquired_at, d.disposed_at AS sold_at,
+		       l.cost_per_share, d.sale_price, d.expenses
+		FROM lot_disposals d
+		JOIN tax_lots l ON l.id = d.lot_id
+		LEFT JOIN instruments i ON i.symbol = l.stock_symbol
+		WHERE l.user_id = $1 AND d.kind = $2
+		  AND d.disposed_at >= $3 AND d.disposed_at < $4
+		ORDER BY d.disposed_at, d.id
+	`, userID, DisposalSell, from, to)
+	if err != nil {
+		return report, err
+	}
+
+	for i := range report.Lots {
+		g := &report.Lots[i]
+		g.CostBasis = round2(g.Quantity * g.CostPerShare)
+		g.SaleValue = round2(g.Quantity * g.SalePrice)
+		g.Gain = round2(g.SaleValue - g.CostBasis - g.Expenses)
+
+		acquired := dateIST(g.AcquiredAt)
+		sold := dateIST(g.SoldAt)
+		g.HoldingDays = int(sold.Sub(acquired).Hours() / 24)
+		g.Term = TermShort
+		summary := &report.ShortTerm
+		if sold.After(acquired.AddDate(0, longTermMonths, 0)) {
+			g.Term = TermLong
+			summary = &report.LongTerm
+		}
+
+		summary.SaleValue += g.SaleValue
+		summary.CostBasis += g.CostBasis
+		summary.Expenses += g.Expenses
+		summary.Gain += g.Gain
+	}
+	for _, summary := range []*GainsSummary{&report.ShortTerm, &report.LongTerm} {
+		summary.SaleValue = round2(summary.SaleValue)
+		summary.CostBasis = round2(summary.CostBasis)
+		summary.Expenses = round2(summary.Expenses)
+		summary.Gain = round2(summary.Gain)
+	}
+
+	return report, nil
+}
+
+func dateIST(t time.Time) time.Time {
+	t = t.In(calendar.IST)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, calendar.IST)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,69 @@
+package tax
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/angad363/stocky-assignment/internal/calendar"
+)
+
+func TestFinancialYear(t *testing.T) {
+	tests := []struct {
+		fy       string
+		from, to string
+		err      error
+	}{
+		{fy: "2025-26", from: "2025-04-01", to: "2026-04-01"},
+		{fy: "1999-00", from: "1999-04-01", to: "2000-04-01"},
+		{fy: "2025-27", err: ErrInvalidFinancialYear},
+		{fy: "2025-2026", err: ErrInvalidFinancialYear},
+		{fy: "2025", err: ErrInvalidFinancialYear},
+		{fy: "", err: ErrInvalidFinancialYear},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fy, func(t *testing.T) {
+			from, to, err := FinancialYear(tt.fy)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if from.Location() != calendar.IST || to.Location() != calendar.IST {
+				t.Errorf("bounds not in IST: %v, %v", from, to)
+			}
+			if got := from.Format("2006-01-02"); got != tt.from {
+				t.Errorf("from = %s, want %s", got, tt.from)
+			}
+			if got := to.Format("2006-01-02"); got != tt.to {
+				t.Errorf("to = %s, want %s", got, tt.to)
+			}
+		})
+	}
+}
+
+func TestCurrentFinancialYear(t *testing.T) {
+	tests := []struct {
+		name string
+		at   time.Time
+		want string
+	}{
+		{"last day of the year", time.Date(2026, time.March, 31, 23, 59, 0, 0, calendar.IST), "2025-26"},
+		{"first day of the year", time.Date(2026, time.April, 1, 0, 0, 0, 0, calendar.IST), "2026-27"},
+		{"April in IST, March in UTC", time.Date(2026, time.March, 31, 19, 0, 0, 0, time.UTC), "2026-27"},
+		{"century rollover", time.Date(2099, time.June, 1, 0, 0, 0, 0, calendar.IST), "2099-00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CurrentFinancialYear(tt.at); got != tt.want {
+				t.Errorf("CurrentFinancialYear(%v) = %s, want %s", tt.at, got, tt.want)
+			}
+		})
+	}
+}
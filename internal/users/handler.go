@@ -2,14 +2,18 @@ package users
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/angad363/stocky-assignment/pkg/logger"
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 )
 
 type RegisterRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	Password string `json:"password" binding:"omitempty,min=8"` // needed to log in
 }
 
 type RegisterResponse struct {
@@ -33,7 +37,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	user, reward, err := h.service.CreateUser(context.Background(), req.Name)
+	user, reward, err := h.service.CreateUser(c.Request.Context(), req.Name, req.Password)
 	if err != nil {
 		logger.Log.Errorf("Failed to create user '%s': %v", req.Name, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
@@ -43,3 +47,166 @@ func (h *UserHandler) Register(c *gin.Context) {
 	logger.Log.WithField("user_name", req.Name).Info("New user registered successfully")
 	c.JSON(http.StatusCreated, RegisterResponse{User: user, Reward: reward})
 }
+
+// GetUser handles GET /users/:id
+func (h *UserHandler) GetUser(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.service.GetUser(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "failed to fetch user")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ListUsers handles GET /users?q=&status=&limit=20&offset=0
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	f := ListFilter{Query: c.Query("q"), Status: c.Query("status")}
+	switch f.Status {
+	case "", StatusActive, StatusDeactivated:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+
+	var err error
+	f.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || f.Limit <= 0 || f.Limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	f.Offset, err = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || f.Offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
+	list, err := h.service.ListUsers(c.Request.Context(), f)
+	if err != nil {
+		h.writeError(c, err, "failed to list users")
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// UpdateUser handles PATCH /users/:id
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	user, err := h.service.UpdateUser(c.Request.Context(), id, req)
+	if err != nil {
+		h.writeError(c, err, "failed to update user")
+		return
+	}
+
+	logger.Log.WithField("user_id", id).Info("User profile updated")
+	c.JSON(http.StatusOK, user)
+}
+
+// DeactivateUser handles POST /users/:id/deactivate
+func (h *UserHandler) DeactivateUser(c *gin.Context) {
+	h.setStatus(c, h.service.Deactivate, "deactivated")
+}
+
+// ReactivateUser handles POST /users/:id/reactivate
+func (h *UserHandler) ReactivateUser(c *gin.Context) {
+	h.setStatus(c, h.service.Reactivate, "reactivated")
+}
+
+func (h *UserHandler) setStatus(c *gin.Context, apply func(context.Context, int) (User, error), verb string) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	user, err := apply(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "failed to update user status")
+		return
+	}
+
+	logger.Log.WithField("user_id", id).Info("User " + verb)
+	c.JSON(http.StatusOK, user)
+}
+
+// SubmitKYC handles POST /users/:id/kyc/submit, sending the user's KYC for review
+func (h *UserHandler) SubmitKYC(c *gin.Context) {
+	h.setKYC(c, KYCRequest{Status: KYCSubmitted})
+}
+
+// SetKYCStatus handles POST /admin/users/:id/kyc
+func (h *UserHandler) SetKYCStatus(c *gin.Context) {
+	var req KYCRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	h.setKYC(c, req)
+}
+
+func (h *UserHandler) setKYC(c *gin.Context, req KYCRequest) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.SetKYCStatus(c.Request.Context(), id, req)
+	if errors.Is(err, ErrInvalidKYCTransition) || errors.Is(err, ErrKYCDetailsMissing) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.writeError(c, err, "failed to update KYC status")
+		return
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"user_id":  id,
+		"kyc":      result.User.KYCStatus,
+		"released": len(result.ReleasedRewards),
+	}).Info("User KYC status updated")
+	c.JSON(http.StatusOK, result)
+}
+
+func userID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *UserHandler) writeError(c *gin.Context, err error, msg string) {
+	var validationErr *ValidationError
+	var pqErr *pq.Error
+
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrKYCFieldsLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		c.JSON(http.StatusConflict, gin.H{"error": "email or PAN is already registered to another user"})
+	default:
+		logger.Log.Errorf("%s: %v", msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
@@ -0,0 +1,126 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/angad363/stocky-assignment/internal/reward"
+)
+
+// KYC states. Rewards for users who are not verified are held until
+// verification releases them.
+const (
+	KYCNotStarted = "not_started"
+	KYCSubmitted  = "submitted"
+	KYCVerified   = "verified"
+	KYCRejected   = "rejected"
+)
+
+var (
+	ErrInvalidKYCTransition = errors.New("invalid KYC status transition")
+	ErrKYCDetailsMissing    = errors.New("KYC details missing")
+)
+
+// kycTransitions lists the states each KYC state may move to
+var kycTransitions = map[string][]string{
+	KYCNotStarted: {KYCSubmitted},
+	KYCSubmitted:  {KYCVerified, KYCRejected},
+	KYCRejected:   {KYCSubmitted},
+	KYCVerified:   {},
+}
+
+type KYCRequest struct {
+	Status string `json:"status" binding:"required"`
+	Note   string `json:"note"`
+}
+
+// KYCResult is the user after a KYC change, with any rewards it released
+type KYCResult struct {
+	User            User  `json:"user"`
+	ReleasedRewards []int `json:"released_rewards"`
+}
+
+// SetKYCStatus moves a user's KYC to a new state. Verification releases
+// the user's held rewards in the same transaction.
+func (s *UserService) SetKYCStatus(ctx context.Context, id int, req KYCRequest) (KYCResult, error) {
+	result := KYCResult{ReleasedRewards: []int{}}
+
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	var before User
+	err = tx.GetContext(ctx, &before, `SELECT `+userColumns+` FROM users WHERE id = $1 FOR UPDATE`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return result, ErrUserNotFound
+	}
+	if err != nil {
+		return result, err
+	}
+	if !canTransitionKYC(before.KYCStatus, req.Status) {
+		return result, fmt.Errorf("%w: %s -> %s", ErrInvalidKYCTransition, before.KYCStatus, req.Status)
+	}
+	// Verified rewards are released to the demat account, and both fields
+	// are locked once submitted, so they must be on file before either
+	if req.Status == KYCSubmitted || req.Status == KYCVerified {
+		var missing []string
+		if before.PAN == "" {
+			missing = append(missing, "pan")
+		}
+		if before.DematAccountID == "" {
+			missing = append(missing, "demat_account_id")
+		}
+		if len(missing) > 0 {
+			return result, fmt.Errorf("%w: %s", ErrKYCDetailsMissing, strings.Join(missing, ", "))
+		}
+	}
+
+	err = tx.GetContext(ctx, &result.User, `
+		UPDATE users SET kyc_status = $2, kyc_updated_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+userColumns,
+		id, req.Status,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	if req.Status == KYCVerified {
+		result.ReleasedRewards, err = reward.ReleaseKYCHolds(ctx, tx.Tx, id)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{
+		Action:     audit.ActionKYCChanged,
+		EntityType: "user",
+		EntityID:   strconv.Itoa(id),
+		Before:     map[string]interface{}{"kyc_status": before.KYCStatus},
+		After: map[string]interface{}{
+			"kyc_status":       req.Status,
+			"note":             req.Note,
+			"released_rewards": result.ReleasedRewards,
+		},
+	})
+	if err != nil {
+		return result, err
+	}
+	return result, tx.Commit()
+}
+
+func canTransitionKYC(from, to string) bool {
+	for _, next := range kycTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
@@ -2,8 +2,51 @@ package users
 
 import "time"
 
+// Account statuses. Deactivated users cannot log in or receive rewards,
+// but their holdings and history are kept.
+const (
+	StatusActive      = "active"
+	StatusDeactivated = "deactivated"
+)
+
 type User struct {
-	ID        int       `db:"id" json:"id"`
-	Name      string    `db:"name" json:"name"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	ID             int        `db:"id" json:"id"`
+	Name           string     `db:"name" json:"name"`
+	Email          string     `db:"email" json:"email"`
+	Phone          string     `db:"phone" json:"phone"`
+	PAN            string     `db:"pan" json:"pan"`
+	DematAccountID string     `db:"demat_account_id" json:"demat_account_id"`
+	Status         string     `db:"status" json:"status"`
+	KYCStatus      string     `db:"kyc_status" json:"kyc_status"`
+	KYCUpdatedAt   *time.Time `db:"kyc_updated_at" json:"kyc_updated_at"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+	DeactivatedAt  *time.Time `db:"deactivated_at" json:"deactivated_at"`
+}
+
+const userColumns = `id, name, email, phone, pan, demat_account_id, status, kyc_status, kyc_updated_at,
+	created_at, COALESCE(updated_at, created_at) AS updated_at, deactivated_at`
+
+// UpdateRequest is a partial profile update; omitted fields are unchanged
+// and an empty string clears an optional field
+type UpdateRequest struct {
+	Name           *string `json:"name"`
+	Email          *string `json:"email"`
+	Phone          *string `json:"phone"`
+	PAN            *string `json:"pan"`
+	DematAccountID *string `json:"demat_account_id"`
+}
+
+type ListFilter struct {
+	Query  string // matches name, email, phone or PAN
+	Status string
+	Limit  int
+	Offset int
+}
+
+type UserList struct {
+	Users  []User `json:"users"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
 }
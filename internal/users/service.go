@@ -2,12 +2,24 @@ package users
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
 
+	"github.com/angad363/stocky-assignment/internal/audit"
+	"github.com/angad363/stocky-assignment/internal/auth"
+	"github.com/angad363/stocky-assignment/internal/events"
 	"github.com/angad363/stocky-assignment/internal/reward"
 	"github.com/jmoiron/sqlx"
 )
 
-// UserService handles user creation and onboarding logic
+var (
+	ErrUserNotFound    = reward.ErrUserNotFound
+	ErrKYCFieldsLocked = errors.New("pan and demat_account_id cannot change while KYC is submitted or verified")
+)
+
+// UserService handles user creation, onboarding and profile management
 type UserService struct {
 	db        *sqlx.DB
 	rewardSvc *reward.RewardService
@@ -17,30 +29,182 @@ func NewUserService(db *sqlx.DB, rewardSvc *reward.RewardService) *UserService {
 	return &UserService{db: db, rewardSvc: rewardSvc}
 }
 
-// CreateUser inserts a new user and triggers an onboarding reward
-func (s *UserService) CreateUser(ctx context.Context, name string) (User, reward.Reward, error) {
+// CreateUser inserts a new user and triggers an onboarding reward. Both are
+// committed together with the user.registered outbox event. Users created
+// without a password cannot log in until one is set.
+func (s *UserService) CreateUser(ctx context.Context, name, password string) (User, reward.Reward, error) {
 	var user User
 	var rwd reward.Reward
 
-	err := s.db.QueryRowContext(ctx,
-		`INSERT INTO users (name) VALUES ($1)
-		RETURNING id, name, created_at`,
-		name,
-	).Scan(&user.ID, &user.Name, &user.CreatedAt)
+	var passwordHash sql.NullString
+	if password != "" {
+		hash, err := auth.HashPassword(password)
+		if err != nil {
+			return user, rwd, err
+		}
+		passwordHash = sql.NullString{String: hash, Valid: true}
+	}
+
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return user, rwd, err
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &user,
+		`INSERT INTO users (name, password_hash) VALUES ($1, $2)
+		RETURNING `+userColumns,
+		name, passwordHash,
+	)
 	if err != nil {
 		return user, rwd, err
 	}
 
+	if err = events.Write(ctx, tx.Tx, events.UserRegistered, user.ID, user); err != nil {
+		return user, rwd, err
+	}
+
 	// Auto-reward new user with 1 share of a random stock
 	rwdReq := reward.RewardRequest{
 		UserID:   user.ID,
 		Quantity: 1.0,
 		Symbol:   "", // random stock
+		Campaign: reward.CampaignOnboarding,
 	}
-	rwd, err = s.rewardSvc.CreateReward(ctx, rwdReq)
+	rwd, err = s.rewardSvc.CreateRewardTx(ctx, tx, rwdReq)
 	if err != nil {
 		return user, rwd, err
 	}
 
-	return user, rwd, nil
-}
\ No newline at end of file
+	return user, rwd, tx.Commit()
+}
+
+func (s *UserService) GetUser(ctx context.Context, id int) (User, error) {
+	var user User
+	err := s.db.GetContext(ctx, &user, `SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, ErrUserNotFound
+	}
+	return user, err
+}
+
+// ListUsers searches users by name, email, phone or PAN, newest first
+func (s *UserService) ListUsers(ctx context.Context, f ListFilter) (UserList, error) {
+	list := UserList{Users: []User{}, Limit: f.Limit, Offset: f.Offset}
+
+	where := `
+		WHERE ($1 = '' OR name ILIKE '%' || $1 || '%' OR email ILIKE '%' || $1 || '%'
+		       OR phone LIKE '%' || $1 || '%' OR pan = UPPER($1))
+		  AND ($2 = '' OR status = $2)`
+	query := strings.TrimSpace(f.Query)
+
+	if err := s.db.GetContext(ctx, &list.Total, `SELECT COUNT(*) FROM users`+where, query, f.Status); err != nil {
+		return list, err
+	}
+	err := s.db.SelectContext(ctx, &list.Users, `
+		SELECT `+userColumns+` FROM users`+where+`
+		ORDER BY id DESC
+		LIMIT $3 OFFSET $4
+	`, query, f.Status, f.Limit, f.Offset)
+	return list, err
+}
+
+// UpdateUser applies a partial profile update. PAN and demat account are
+// what KYC checks, so they are locked while a review is pending and after
+// it passed; sending the current value, or filling in one that is still
+// empty, is accepted.
+func (s *UserService) UpdateUser(ctx context.Context, id int, req UpdateRequest) (User, error) {
+	if err := normalize(&req); err != nil {
+		return User{}, err
+	}
+
+	guard := func(before User) error {
+		if before.KYCStatus != KYCSubmitted && before.KYCStatus != KYCVerified {
+			return nil
+		}
+		if (req.PAN != nil && before.PAN != "" && *req.PAN != before.PAN) ||
+			(req.DematAccountID != nil && before.DematAccountID != "" && *req.DematAccountID != before.DematAccountID) {
+			return ErrKYCFieldsLocked
+		}
+		return nil
+	}
+
+	return s.update(ctx, id, audit.ActionUserUpdated, guard, `
+		UPDATE users SET
+			name             = COALESCE($2, name),
+			email            = COALESCE($3, email),
+			phone            = COALESCE($4, phone),
+			pan              = COALESCE($5, pan),
+			demat_account_id = COALESCE($6, demat_account_id),
+			updated_at       = NOW()
+		WHERE id = $1
+		RETURNING `+userColumns,
+		req.Name, req.Email, req.Phone, req.PAN, req.DematAccountID,
+	)
+}
+
+// Deactivate blocks login and new rewards, and revokes refresh tokens.
+// Access tokens already issued stay valid until they expire.
+func (s *UserService) Deactivate(ctx context.Context, id int) (User, error) {
+	return s.update(ctx, id, audit.ActionUserDeactivated, nil, `
+		WITH revoked AS (
+			UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL
+		)
+		UPDATE users SET status = $2, deactivated_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+userColumns,
+		StatusDeactivated,
+	)
+}
+
+func (s *UserService) Reactivate(ctx context.Context, id int) (User, error) {
+	return s.update(ctx, id, audit.ActionUserReactivated, nil, `
+		UPDATE users SET status = $2, deactivated_at = NULL, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+userColumns,
+		StatusActive,
+	)
+}
+
+// update runs an UPDATE ... RETURNING for one user ($1) and audits the
+// before and after profile. guard, if set, can refuse the change after
+// seeing the locked row.
+func (s *UserService) update(ctx context.Context, id int, action string, guard func(User) error,
+	query string, args ...interface{}) (User, error) {
+	var before, user User
+
+	tx, err := audit.Begin(ctx, s.db)
+	if err != nil {
+		return user, err
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &before, `SELECT `+userColumns+` FROM users WHERE id = $1 FOR UPDATE`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, ErrUserNotFound
+	}
+	if err != nil {
+		return user, err
+	}
+	if guard != nil {
+		if err := guard(before); err != nil {
+			return before, err
+		}
+	}
+
+	if err := tx.GetContext(ctx, &user, query, append([]interface{}{id}, args...)...); err != nil {
+		return user, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{
+		Action:     action,
+		EntityType: "user",
+		EntityID:   strconv.Itoa(id),
+		Before:     before,
+		After:      user,
+	})
+	if err != nil {
+		return user, err
+	}
+	return user, tx.Commit()
+}
@@ -0,0 +1,65 @@
+package users
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+var (
+	// PAN: five letters, four digits, one letter, e.g. ABCDE1234F
+	panPattern = regexp.MustCompile(`^[A-Z]{5}[0-9]{4}[A-Z]$`)
+	// Indian mobile numbers, optionally with +91
+	phonePattern = regexp.MustCompile(`^(\+91)?[6-9][0-9]{9}$`)
+	// NSDL (IN + 14 digits) or CDSL (16 digits) beneficiary owner IDs
+	dematPattern = regexp.MustCompile(`^(IN[0-9]{14}|[0-9]{16})$`)
+)
+
+// ValidationError is returned when a profile field is malformed
+type ValidationError struct {
+	Field string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s", e.Field)
+}
+
+// normalize trims and upper-cases fields in place so stored values are
+// canonical, then validates every field that is being set
+func normalize(req *UpdateRequest) error {
+	trim := func(v *string, upper bool) {
+		if v != nil {
+			*v = strings.TrimSpace(*v)
+			if upper {
+				*v = strings.ToUpper(*v)
+			}
+		}
+	}
+	trim(req.Name, false)
+	trim(req.Email, false)
+	trim(req.Phone, false)
+	trim(req.PAN, true)
+	trim(req.DematAccountID, true)
+
+	if req.Name != nil && (*req.Name == "" || len(*req.Name) > 255) {
+		return &ValidationError{Field: "name"}
+	}
+	if req.Email != nil && *req.Email != "" {
+		addr, err := mail.ParseAddress(*req.Email)
+		if err != nil || addr.Address != *req.Email {
+			return &ValidationError{Field: "email"}
+		}
+		*req.Email = strings.ToLower(*req.Email)
+	}
+	if req.Phone != nil && *req.Phone != "" && !phonePattern.MatchString(*req.Phone) {
+		return &ValidationError{Field: "phone"}
+	}
+	if req.PAN != nil && *req.PAN != "" && !panPattern.MatchString(*req.PAN) {
+		return &ValidationError{Field: "pan"}
+	}
+	if req.DematAccountID != nil && *req.DematAccountID != "" && !dematPattern.MatchString(*req.DematAccountID) {
+		return &ValidationError{Field: "demat_account_id"}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// maxBackoff caps the delay between retries
+const maxBackoff = 6 * time.Hour
+
+// Dispatcher POSTs pending deliveries to subscriber URLs, retrying with
+// exponential backoff until maxAttempts, after which a delivery is dead
+type Dispatcher struct {
+	db          *sqlx.DB
+	client      *http.Client
+	maxAttempts int
+	backoffBase time.Duration
+	batchSize   int
+}
+
+func NewDispatcher(db *sqlx.DB, client *http.Client, maxAttempts int, backoffBase time.Duration) *Dispatcher {
+	return &Dispatcher{
+		db:          db,
+		client:      client,
+		maxAttempts: maxAttempts,
+		backoffBase: backoffBase,
+		batchSize:   50,
+	}
+}
+
+// envelope is the JSON body receivers get
+type envelope struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// RunOnce claims due deliveries and attempts each of them once. Claimed
+// rows are leased past the HTTP timeout so parallel workers skip them.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	lease := d.client.Timeout + 30*time.Second
+
+	targets := []deliveryTarget{}
+	err := d.db.SelectContext(ctx, &targets, `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + $3 * INTERVAL '1 second'
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id
+		  AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $1 AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		  )
+		RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status,
+		          d.attempts, d.next_attempt_at, d.last_status_code, d.last_error,
+		          d.created_at, d.delivered_at, s.url, s.secret
+	`, DeliveryPending, d.batchSize, lease.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, t := range targets {
+		code, sendErr := d.send(ctx, t)
+		if err := d.record(ctx, t.Delivery, code, sendErr); err != nil {
+			return len(targets), err
+		}
+	}
+	return len(targets), nil
+}
+
+func (d *Dispatcher) send(ctx context.Context, t deliveryTarget) (int, error) {
+	body, err := json.Marshal(envelope{
+		ID:        t.EventID,
+		Type:      t.EventType,
+		CreatedAt: t.CreatedAt,
+		Data:      t.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Stocky-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, t.EventType)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(t.ID))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(t.Secret, ts, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) record(ctx context.Context, delivery Delivery, code int, sendErr error) error {
+	attempts := delivery.Attempts + 1
+
+	if sendErr == nil {
+		_, err := d.db.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = $2, attempts = $3, last_status_code = $4, last_error = '', delivered_at = NOW()
+			WHERE id = $1
+		`, delivery.ID, DeliverySucceeded, attempts, code)
+		return err
+	}
+
+	status := DeliveryPending
+	if attempts >= d.maxAttempts {
+		status = DeliveryDead
+	}
+	next := time.Now().Add(d.backoff(attempts))
+
+	logger.Log.WithFields(map[string]interface{}{
+		"delivery_id": delivery.ID,
+		"attempts":    attempts,
+		"status":      status,
+	}).Warnf("Webhook delivery failed: %v", sendErr)
+
+	_, err := d.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, last_status_code = $4, last_error = $5, next_attempt_at = $6
+		WHERE id = $1
+	`, delivery.ID, status, attempts, code, sendErr.Error(), next)
+	return err
+}
+
+// backoff doubles the base delay with every failed attempt
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := time.Duration(float64(d.backoffBase) * math.Pow(2, float64(attempts-1)))
+	if delay > maxBackoff || delay <= 0 {
+		return maxBackoff
+	}
+	return delay
+}
+
+// StartDispatcher polls for due deliveries on the given interval
+func StartDispatcher(dispatcher *Dispatcher, interval time.Duration) {
+	logger.Log.WithField("interval", interval.String()).Info("🪝 Webhook dispatcher started")
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			if _, err := dispatcher.RunOnce(context.Background()); err != nil {
+				logger.Log.Errorf("Webhook dispatch run failed: %v", err)
+			}
+		}
+	}()
+}
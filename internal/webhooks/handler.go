@@ -0,0 +1,164 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/angad363/stocky-assignment/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	service *WebhookService
+}
+
+func NewWebhookHandler(service *WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// CreateSubscription handles POST /admin/webhooks. The secret is only
+// returned in this response.
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Log.Warnf("Invalid webhook subscription request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	sub, err := h.service.CreateSubscription(context.Background(), req)
+	if err != nil {
+		h.writeError(c, err, "failed to create webhook subscription")
+		return
+	}
+
+	logger.Log.WithField("subscription_id", sub.ID).Info("Webhook subscription created")
+	c.JSON(http.StatusCreated, sub)
+}
+
+func (h *WebhookHandler) ListSubscriptions(c *gin.Context) {
+	subs, err := h.service.ListSubscriptions(context.Background())
+	if err != nil {
+		h.writeError(c, err, "failed to list webhook subscriptions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subs})
+}
+
+func (h *WebhookHandler) GetSubscription(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.GetSubscription(context.Background(), id)
+	if err != nil {
+		h.writeError(c, err, "failed to fetch webhook subscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *WebhookHandler) UpdateSubscription(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Log.Warnf("Invalid webhook subscription request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	sub, err := h.service.UpdateSubscription(context.Background(), id, req)
+	if err != nil {
+		h.writeError(c, err, "failed to update webhook subscription")
+		return
+	}
+
+	logger.Log.WithField("subscription_id", id).Info("Webhook subscription updated")
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSubscription(context.Background(), id); err != nil {
+		h.writeError(c, err, "failed to delete webhook subscription")
+		return
+	}
+
+	logger.Log.WithField("subscription_id", id).Info("Webhook subscription deactivated")
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries handles GET /admin/webhooks/:id/deliveries?status=dead&limit=50
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	deliveries, err := h.service.ListDeliveries(context.Background(), id, c.Query("status"), limit)
+	if err != nil {
+		h.writeError(c, err, "failed to list webhook deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// ReplayDelivery handles POST /admin/webhook-deliveries/:id/replay
+func (h *WebhookHandler) ReplayDelivery(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.ReplayDelivery(context.Background(), id)
+	if err != nil {
+		h.writeError(c, err, "failed to replay webhook delivery")
+		return
+	}
+
+	logger.Log.WithField("delivery_id", id).Info("Webhook delivery queued for replay")
+	c.JSON(http.StatusOK, delivery)
+}
+
+func paramID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *WebhookHandler) writeError(c *gin.Context, err error, msg string) {
+	var validationErr *ValidationError
+
+	switch {
+	case errors.Is(err, ErrSubscriptionNotFound), errors.Is(err, ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logger.Log.Errorf("%s: %v", msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
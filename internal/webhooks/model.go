@@ -0,0 +1,58 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+type Subscription struct {
+	ID         int            `db:"id" json:"id"`
+	URL        string         `db:"url" json:"url"`
+	EventTypes pq.StringArray `db:"event_types" json:"event_types"`
+	Secret     string         `db:"secret" json:"-"`
+	Active     bool           `db:"active" json:"active"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+}
+
+// SubscriptionWithSecret is returned once, when a subscription is created
+type SubscriptionWithSecret struct {
+	Subscription
+	Secret string `json:"secret"`
+}
+
+type SubscriptionRequest struct {
+	URL        string   `json:"url" binding:"required"`
+	EventTypes []string `json:"event_types" binding:"required"`
+	Secret     string   `json:"secret"`
+	Active     *bool    `json:"active"`
+}
+
+type Delivery struct {
+	ID             int             `db:"id" json:"id"`
+	SubscriptionID int             `db:"subscription_id" json:"subscription_id"`
+	EventID        int64           `db:"event_id" json:"event_id"`
+	EventType      string          `db:"event_type" json:"event_type"`
+	Payload        json.RawMessage `db:"payload" json:"payload"`
+	Status         string          `db:"status" json:"status"`
+	Attempts       int             `db:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time       `db:"next_attempt_at" json:"next_attempt_at"`
+	LastStatusCode int             `db:"last_status_code" json:"last_status_code"`
+	LastError      string          `db:"last_error" json:"last_error"`
+	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
+	DeliveredAt    *time.Time      `db:"delivered_at" json:"delivered_at"`
+}
+
+// deliveryTarget is a claimed delivery joined with its subscription
+type deliveryTarget struct {
+	Delivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
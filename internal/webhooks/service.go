@@ -0,0 +1,192 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/angad363/stocky-assignment/internal/events"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+)
+
+// knownEvents are the event types a subscription may ask for
+var knownEvents = map[string]bool{
+	events.RewardCreated:     true,
+	events.RewardReversed:    true,
+	events.UserRegistered:    true,
+	events.ReferralConverted: true,
+}
+
+// WebhookService manages subscriptions and their delivery records
+type WebhookService struct {
+	db *sqlx.DB
+}
+
+func NewWebhookService(db *sqlx.DB) *WebhookService {
+	return &WebhookService{db: db}
+}
+
+const subscriptionColumns = `id, url, event_types, secret, active, created_at`
+
+const deliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+	next_attempt_at, last_status_code, last_error, created_at, delivered_at`
+
+func (s *WebhookService) CreateSubscription(ctx context.Context, req SubscriptionRequest) (SubscriptionWithSecret, error) {
+	var sub SubscriptionWithSecret
+
+	if err := validateSubscription(req); err != nil {
+		return sub, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return sub, err
+		}
+		secret = hex.EncodeToString(buf)
+	}
+	active := req.Active == nil || *req.Active
+
+	err := s.db.GetContext(ctx, &sub.Subscription, `
+		INSERT INTO webhook_subscriptions (url, event_types, secret, active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+subscriptionColumns,
+		req.URL, pq.StringArray(req.EventTypes), secret, active,
+	)
+	sub.Secret = sub.Subscription.Secret
+	return sub, err
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	subs := []Subscription{}
+	err := s.db.SelectContext(ctx, &subs, `
+		SELECT `+subscriptionColumns+` FROM webhook_subscriptions ORDER BY id
+	`)
+	return subs, err
+}
+
+func (s *WebhookService) GetSubscription(ctx context.Context, id int) (Subscription, error) {
+	var sub Subscription
+	err := s.db.GetContext(ctx, &sub, `
+		SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE id = $1
+	`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sub, ErrSubscriptionNotFound
+	}
+	return sub, err
+}
+
+// UpdateSubscription replaces URL, event types and active flag; the secret
+// is rotated only when a new one is supplied
+func (s *WebhookService) UpdateSubscription(ctx context.Context, id int, req SubscriptionRequest) (Subscription, error) {
+	var sub Subscription
+
+	if err := validateSubscription(req); err != nil {
+		return sub, err
+	}
+	active := req.Active == nil || *req.Active
+
+	err := s.db.GetContext(ctx, &sub, `
+		UPDATE webhook_subscriptions
+		SET url = $2, event_types = $3, active = $4, secret = COALESCE(NULLIF($5, ''), secret)
+		WHERE id = $1
+		RETURNING `+subscriptionColumns,
+		id, req.URL, pq.StringArray(req.EventTypes), active, req.Secret,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sub, ErrSubscriptionNotFound
+	}
+	return sub, err
+}
+
+// DeleteSubscription deactivates a subscription; its delivery history is kept
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id int) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE webhook_subscriptions SET active = FALSE WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, subscriptionID int, status string, limit int) ([]Delivery, error) {
+	deliveries := []Delivery{}
+
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE subscription_id = $1`
+	args := []interface{}{subscriptionID}
+	if status != "" {
+		query += ` AND status = $2`
+		args = append(args, status)
+	}
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT %d`, limit)
+
+	err := s.db.SelectContext(ctx, &deliveries, query, args...)
+	return deliveries, err
+}
+
+// ReplayDelivery queues a delivery again regardless of its current state
+func (s *WebhookService) ReplayDelivery(ctx context.Context, id int) (Delivery, error) {
+	var d Delivery
+	err := s.db.GetContext(ctx, &d, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = 0, next_attempt_at = NOW(), last_error = ''
+		WHERE id = $1
+		RETURNING `+deliveryColumns,
+		id, DeliveryPending,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return d, ErrDeliveryNotFound
+	}
+	return d, err
+}
+
+// Enqueue creates one pending delivery per active subscription to the
+// event's type. It is safe to call again for the same event.
+func (s *WebhookService) Enqueue(ctx context.Context, event events.Event) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status)
+		SELECT id, $1, $2, $3, $4
+		FROM webhook_subscriptions
+		WHERE active AND $2 = ANY(event_types)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`, event.ID, event.Type, event.Payload, DeliveryPending)
+	return err
+}
+
+// ValidationError is returned for malformed subscription requests
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Reason
+}
+
+func validateSubscription(req SubscriptionRequest) error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &ValidationError{Reason: "url must be an absolute http(s) URL"}
+	}
+	if len(req.EventTypes) == 0 {
+		return &ValidationError{Reason: "at least one event type is required"}
+	}
+	for _, t := range req.EventTypes {
+		if !knownEvents[t] {
+			return &ValidationError{Reason: fmt.Sprintf("unknown event type %q", t)}
+		}
+	}
+	return nil
+}
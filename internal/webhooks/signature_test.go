@@ -0,0 +1,62 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"reward.created"}`)
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("whsec_test", 1700000000, body); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if Sign("whsec_other", 1700000000, body) == want {
+		t.Error("signature does not depend on the secret")
+	}
+	if Sign("whsec_test", 1700000001, body) == want {
+		t.Error("signature does not depend on the timestamp")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"type":"reward.created"}`)
+	now := time.Now().Unix()
+	stale := time.Now().Add(-10 * time.Minute).Unix()
+	ts := strconv.FormatInt(now, 10)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		tolerance time.Duration
+		want      bool
+	}{
+		{"valid", secret, ts, Sign(secret, now, body), body, 5 * time.Minute, true},
+		{"wrong secret", "whsec_other", ts, Sign(secret, now, body), body, 5 * time.Minute, false},
+		{"tampered body", secret, ts, Sign(secret, now, body), []byte(`{}`), 5 * time.Minute, false},
+		{"timestamp not signed", secret, strconv.FormatInt(now+1, 10), Sign(secret, now, body), body, 5 * time.Minute, false},
+		{"too old", secret, strconv.FormatInt(stale, 10), Sign(secret, stale, body), body, 5 * time.Minute, false},
+		{"no tolerance accepts old", secret, strconv.FormatInt(stale, 10), Sign(secret, stale, body), body, 0, true},
+		{"missing prefix", secret, ts, Sign(secret, now, body)[len("sha256="):], body, 5 * time.Minute, false},
+		{"bad timestamp", secret, "yesterday", Sign(secret, now, body), body, 5 * time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.timestamp, tt.signature, tt.body, tt.tolerance); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package webhooks
+
+import (
+	"context"
+
+	"github.com/angad363/stocky-assignment/internal/events"
+)
+
+// Sink fans outbox events out into webhook deliveries
+type Sink struct {
+	service *WebhookService
+}
+
+func NewSink(service *WebhookService) *Sink {
+	return &Sink{service: service}
+}
+
+func (s *Sink) Name() string {
+	return "webhook"
+}
+
+func (s *Sink) Publish(ctx context.Context, event events.Event) error {
+	return s.service.Enqueue(ctx, event)
+}